@@ -2,44 +2,134 @@ package batcher
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+var (
+	// ErrClosed is returned when adding items to a closed Batcher.
+	ErrClosed = errors.New("batcher is closed")
+	// ErrFull is returned when adding items to a full queue with OverflowReject policy.
+	ErrFull = errors.New("batcher queue is full")
+	// ErrDropped is reported by Result when the items were discarded due to the queue overflow.
+	ErrDropped = errors.New("batcher items dropped")
+)
+
 // Batcher represents a batching mechanism that collects items and flushes them
 // either when a maximum count is reached or after a timeout period.
 // The generic type T represents the type of items to be batched.
 type Batcher[T any] struct {
 	ctx       context.Context
 	cancel    context.CancelFunc
+	done      chan struct{}
+	closing   chan struct{} // closed before closeMu is locked for closing
+	closeOnce sync.Once
+	closeMu   sync.RWMutex
+	closed    bool // protected by closeMu
 	items     []T
-	itemsCh   chan []T
+	weight    int
+	results   []*Result  // results waiting for the current batch
+	refs      []*segment // spool segments of the current batch items
+	oldest    time.Time  // time of adding the oldest item of the current batch
+	stats     stats
+	itemsCh   chan entry[T]
+	flushReqs chan chan []*batch[T]
+	flushed   *[]*batch[T] // collects batches created while serving a Flush call
+	batches   chan *batch[T]
+	slots     chan struct{} // held by every batch in flight
+	pending   atomic.Int64  // number of added items, which are not flushed yet
 	maxCount  int
 	timeout   time.Duration
-	ticker    *time.Ticker
-	flushFunc FlushFunc[T]
+	ticker    Ticker
+	flushFunc FlushErrorFunc[T]
+	config    config[T]
 	waitGroup sync.WaitGroup
+
+	inflightMu sync.Mutex
+	inflight   map[*batch[T]]struct{} // batches handed over to flush workers
+}
+
+// entry is a set of items added by a single call.
+type entry[T any] struct {
+	items  []T
+	result *Result  // nil if the caller doesn't wait for the flush
+	ref    *segment // spool segment of the items
+	added  time.Time
+}
+
+// batch is a set of items flushed together.
+type batch[T any] struct {
+	items   []T
+	results []*Result
+	refs    []*segment
+	weight  int
+	reason  FlushReason
+	oldest  time.Time
+	done    chan struct{} // closed when the flush is complete
+	err     error         // error of the last flush attempt
 }
 
 // FlushFunc defines the signature for the function that will be called
 // when the batch is flushed. It receives the context and the batched items.
 type FlushFunc[T any] func(context.Context, []T)
 
+// FlushErrorFunc is like FlushFunc, but it also reports whether the batch was flushed.
+// A failed batch is retried according to the RetryPolicy of the Batcher.
+type FlushErrorFunc[T any] func(context.Context, []T) error
+
 // New creates and starts a new Batcher instance.
 // maxCount specifies the maximum number of items to collect before flushing.
 // timeout specifies the maximum duration to wait before flushing.
 // fun is the function that will be called when the batch is flushed.
 // Returns a pointer to the newly created Batcher.
-func New[T any](maxCount int, timeout time.Duration, fun FlushFunc[T]) *Batcher[T] {
+func New[T any](maxCount int, timeout time.Duration, fun FlushFunc[T], options ...Option[T]) *Batcher[T] {
+	return NewWithError(maxCount, timeout, func(ctx context.Context, items []T) error {
+		fun(ctx, items)
+		return nil
+	}, options...)
+}
+
+// NewWithError creates and starts a new Batcher instance,
+// which flushes batches using the error-returning function.
+// See New for the description of the other arguments.
+func NewWithError[T any](maxCount int, timeout time.Duration, fun FlushErrorFunc[T], options ...Option[T]) *Batcher[T] {
+	cfg := newConfig(options)
 	ctx, cancel := context.WithCancel(context.Background())
 	b := &Batcher[T]{
 		ctx:       ctx,
 		cancel:    cancel,
-		itemsCh:   make(chan []T),
+		done:      make(chan struct{}),
+		closing:   make(chan struct{}),
+		itemsCh:   make(chan entry[T], cfg.QueueSize),
+		flushReqs: make(chan chan []*batch[T]),
 		maxCount:  maxCount,
 		timeout:   timeout,
-		ticker:    time.NewTicker(timeout),
+		ticker:    cfg.Clock.NewTicker(timeout),
 		flushFunc: fun,
+		config:    cfg,
+	}
+
+	if workers := b.config.Workers; workers > 0 {
+		b.batches = make(chan *batch[T], b.config.MaxInFlight)
+		b.slots = make(chan struct{}, b.config.MaxInFlight)
+		b.inflight = make(map[*batch[T]]struct{})
+		for range workers {
+			b.waitGroup.Add(1)
+			go func() {
+				defer b.waitGroup.Done()
+				for bt := range b.batches {
+					b.deliver(bt)
+					b.inflightMu.Lock()
+					delete(b.inflight, bt)
+					b.inflightMu.Unlock()
+					<-b.slots
+				}
+			}()
+		}
 	}
 
 	b.waitGroup.Add(1)
@@ -52,50 +142,406 @@ func New[T any](maxCount int, timeout time.Duration, fun FlushFunc[T]) *Batcher[
 }
 
 // Add adds one or more items to the batch. The operation respects the provided context.
-// Returns an error if the context is canceled before the items can be added.
+// Returns an error if the context is canceled before the items can be added,
+// or ErrClosed if the Batcher is closed.
+// If the queue is full, the behaviour depends on the OverflowPolicy of the Batcher.
 func (b *Batcher[T]) Add(ctx context.Context, items ...T) error {
+	return b.add(ctx, entry[T]{items: items})
+}
+
+// AddWait is like Add, but it also returns a Result,
+// which is resolved when the items are flushed.
+func (b *Batcher[T]) AddWait(ctx context.Context, items ...T) (*Result, error) {
+	result := newResult()
+	if err := b.add(ctx, entry[T]{items: items, result: result}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (b *Batcher[T]) add(ctx context.Context, e entry[T]) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
+
+	e.added = b.config.Clock.Now()
+	select {
+	case <-b.closing:
+		return ErrClosed
+	default:
+	}
+
+	// The read lock guarantees that the run loop receives the items before it is stopped.
+	// It is never held for long, since enqueue gives up when the Batcher starts closing.
+	b.closeMu.RLock()
+	defer b.closeMu.RUnlock()
+	if b.closed {
+		return ErrClosed
+	}
+
+	if spool := b.config.Spool; spool != nil {
+		ref, err := spool.append(e.items)
+		if err != nil {
+			return fmt.Errorf("spool: %w", err)
+		}
+		e.ref = ref
+	}
+	if err := b.enqueue(ctx, e); err != nil {
+		b.unspool(e)
+		return err
+	}
+	return nil
+}
+
+// enqueue puts the entry to the queue according to the overflow policy.
+func (b *Batcher[T]) enqueue(ctx context.Context, e entry[T]) error {
+	switch b.config.Overflow {
+	case OverflowDropNewest, OverflowReject:
+		select {
+		case b.itemsCh <- e:
+			b.pending.Add(int64(len(e.items)))
+			return nil
+		default:
+		}
+		if b.config.Overflow == OverflowReject {
+			return ErrFull
+		}
+		b.drop(e)
+		return nil
+	case OverflowDropOldest:
+		for {
+			select {
+			case b.itemsCh <- e:
+				b.pending.Add(int64(len(e.items)))
+				return nil
+			default:
+			}
+			select {
+			case dropped := <-b.itemsCh:
+				b.pending.Add(-int64(len(dropped.items)))
+				b.drop(dropped)
+			default:
+			}
+		}
+	}
+
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case b.itemsCh <- items:
+	case <-b.closing:
+		return ErrClosed
+	case b.itemsCh <- e:
+		b.pending.Add(int64(len(e.items)))
 		return nil
 	}
 }
 
+// drop discards the entry due to the queue overflow.
+func (b *Batcher[T]) drop(e entry[T]) {
+	b.stats.dropped(len(e.items))
+	if b.config.Hooks != nil {
+		b.config.Hooks.OnDrop(len(e.items))
+	}
+	if e.result != nil {
+		e.result.release(ErrDropped)
+	}
+	b.unspool(e)
+}
+
+// unspool removes the entry items, which won't be flushed, from the spool.
+func (b *Batcher[T]) unspool(e entry[T]) {
+	if e.ref != nil {
+		b.config.Spool.ack(slices.Repeat([]*segment{e.ref}, len(e.items)))
+	}
+}
+
+// replay appends the items recovered from the spool to the batch.
+func (b *Batcher[T]) replay() {
+	if b.config.Spool == nil {
+		return
+	}
+
+	now := b.config.Clock.Now()
+	recovered := b.config.Spool.replay()
+	for len(recovered) > 0 {
+		// Consecutive items of the same segment make up an entry,
+		// which fits into the batch, since it is appended as a whole
+		room := max(b.maxCount-len(b.items), 1)
+		n := 1
+		for n < min(len(recovered), room) && recovered[n].ref == recovered[0].ref {
+			n++
+		}
+		e := entry[T]{ref: recovered[0].ref, added: now}
+		for _, it := range recovered[:n] {
+			e.items = append(e.items, it.value)
+		}
+		recovered = recovered[n:]
+
+		b.pending.Add(int64(len(e.items)))
+		b.append(e)
+	}
+}
+
+// Flush flushes all previously added items and waits until the flush is complete.
+// Returns the joined errors of the failed batches, a context error if
+// the context is canceled before the flush is complete, or ErrClosed
+// if the Batcher is closed.
+func (b *Batcher[T]) Flush(ctx context.Context) error {
+	reply := make(chan []*batch[T], 1)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-b.done:
+		return ErrClosed
+	case b.flushReqs <- reply:
+	}
+
+	var batches []*batch[T]
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case batches = <-reply:
+	}
+
+	errs := make([]error, 0, len(batches))
+	for _, bt := range batches {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-bt.done:
+			errs = append(errs, bt.err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // run is the main processing loop that handles adding items, timeouts, and shutdown.
 func (b *Batcher[T]) run() {
+	defer b.ticker.Stop()
+	b.replay()
 	for {
 		select {
-		case <-b.ctx.Done():
-			b.flush()
-			return
-		case <-b.ticker.C:
-			b.flush()
-		case items := <-b.itemsCh:
-			b.items = append(b.items, items...)
-			if len(b.items) >= b.maxCount {
-				b.flush()
+		case <-b.done:
+			b.drain()
+			b.flush(FlushReasonClose)
+			if b.batches != nil {
+				close(b.batches)
 			}
+			return
+		case <-b.ticker.C():
+			b.flush(FlushReasonTimeout)
+		case e := <-b.itemsCh:
+			b.append(e)
+		case reply := <-b.flushReqs:
+			batches := b.inflightBatches()
+			b.flushed = &batches
+			b.drain()
+			b.flush(FlushReasonManual)
+			b.flushed = nil
+			reply <- batches
+		}
+	}
+}
+
+// drain appends all queued items to the batch.
+func (b *Batcher[T]) drain() {
+	for range len(b.itemsCh) {
+		b.append(<-b.itemsCh)
+	}
+}
+
+// append adds items to the current batch and flushes it when the limits are reached.
+// When the items are weighed, the batch is flushed before it would exceed
+// the maximum weight, so an oversized item is flushed on its own.
+func (b *Batcher[T]) append(e entry[T]) {
+	if e.result != nil {
+		defer e.result.release(nil)
+	}
+
+	if b.config.Weigh == nil {
+		if len(b.items) == 0 {
+			b.oldest = e.added
+		}
+		b.items = append(b.items, e.items...)
+		if e.ref != nil {
+			b.refs = append(b.refs, slices.Repeat([]*segment{e.ref}, len(e.items))...)
+		}
+		b.await(e.result)
+		if len(b.items) >= b.maxCount {
+			b.flush(FlushReasonCount)
+		}
+		return
+	}
+
+	for _, item := range e.items {
+		weight := b.config.Weigh(item)
+		if len(b.items) > 0 && b.weight+weight > b.config.MaxWeight {
+			b.flush(FlushReasonWeight)
+		}
+		if len(b.items) == 0 {
+			b.oldest = e.added
+		}
+		b.items = append(b.items, item)
+		if e.ref != nil {
+			b.refs = append(b.refs, e.ref)
 		}
+		b.weight += weight
+		b.await(e.result)
+		switch {
+		case b.weight >= b.config.MaxWeight:
+			b.flush(FlushReasonWeight)
+		case len(b.items) >= b.maxCount:
+			b.flush(FlushReasonCount)
+		}
+	}
+}
+
+// await makes the result wait for the current batch.
+func (b *Batcher[T]) await(result *Result) {
+	if result == nil || (len(b.results) > 0 && b.results[len(b.results)-1] == result) {
+		return
 	}
+	result.hold()
+	b.results = append(b.results, result)
 }
 
 // flush calls the flush function with the current batch of items and resets the collection.
-func (b *Batcher[T]) flush() {
-	if len(b.items) > 0 {
-		b.flushFunc(b.ctx, b.items)
-		b.items = nil
-		b.ticker.Reset(b.timeout)
+// If there are flush workers, the batch is handed over to them instead.
+// It blocks while the maximum number of batches is in flight.
+func (b *Batcher[T]) flush(reason FlushReason) {
+	if len(b.items) == 0 {
+		return
+	}
+	if b.slots != nil {
+		b.slots <- struct{}{} // the items stay in the current batch meanwhile
+	}
+
+	bt := &batch[T]{
+		items:   b.items,
+		results: b.results,
+		refs:    b.refs,
+		weight:  b.weight,
+		reason:  reason,
+		oldest:  b.oldest,
+		done:    make(chan struct{}),
+	}
+	if b.flushed != nil {
+		*b.flushed = append(*b.flushed, bt)
+	}
+	if b.batches != nil {
+		b.inflightMu.Lock()
+		b.inflight[bt] = struct{}{}
+		b.inflightMu.Unlock()
+		b.batches <- bt
+	} else {
+		b.deliver(bt)
+	}
+	b.items = nil
+	b.results = nil
+	b.refs = nil
+	b.weight = 0
+	b.ticker.Reset(b.timeout)
+}
+
+// inflightBatches returns the batches being flushed by the workers.
+func (b *Batcher[T]) inflightBatches() []*batch[T] {
+	b.inflightMu.Lock()
+	defer b.inflightMu.Unlock()
+	batches := make([]*batch[T], 0, len(b.inflight))
+	for bt := range b.inflight {
+		batches = append(batches, bt)
+	}
+	return batches
+}
+
+// deliver flushes the batch retrying on failures.
+// The items are passed to the dead letter function if all attempts have failed.
+func (b *Batcher[T]) deliver(bt *batch[T]) {
+	start := b.config.Clock.Now()
+	attempts := 0
+	bt.err = b.config.Retry.do(b.ctx, b.config.Clock, func() error {
+		attempts++
+		return b.flushFunc(b.ctx, bt.items)
+	})
+	if bt.err != nil && b.config.DeadLetter != nil {
+		b.config.DeadLetter(b.ctx, bt.items, bt.err)
+	}
+	if len(bt.refs) > 0 && (bt.err == nil || b.config.DeadLetter != nil) {
+		b.config.Spool.ack(bt.refs)
+	}
+	b.pending.Add(-int64(len(bt.items)))
+
+	info := FlushInfo{
+		Size:      len(bt.items),
+		Weight:    bt.weight,
+		Reason:    bt.reason,
+		QueueWait: start.Sub(bt.oldest),
+		Latency:   b.config.Clock.Now().Sub(start),
+		Attempts:  attempts,
+		Err:       bt.err,
+	}
+	b.stats.flushed(info)
+	if b.config.Hooks != nil {
+		b.config.Hooks.OnFlush(info)
+	}
+	for _, result := range bt.results {
+		result.release(bt.err)
+	}
+	close(bt.done)
+}
+
+// Stats returns a snapshot of the Batcher statistics.
+func (b *Batcher[T]) Stats() Stats {
+	snapshot := b.stats.snapshot()
+	snapshot.Pending = b.pending.Load()
+	return snapshot
+}
+
+// ShutdownError is returned by Shutdown if it gave up on flushing the remaining items.
+type ShutdownError struct {
+	Unflushed int   // Number of items, which weren't flushed.
+	Err       error // Context error.
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("shutdown: %d items left unflushed: %v", e.Unflushed, e.Err)
+}
+
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
+// Shutdown stops the batch processor and flushes any remaining items.
+// It blocks until all pending operations, including in-flight flushes, are complete
+// or the context is canceled. In the latter case the context passed to the flush function
+// is canceled as well, and ShutdownError reports the number of items left unflushed.
+func (b *Batcher[T]) Shutdown(ctx context.Context) error {
+	b.closeOnce.Do(func() { close(b.closing) }) // wake up blocked Add calls
+
+	stopped := make(chan struct{})
+	go func() {
+		b.closeMu.Lock()
+		if !b.closed {
+			b.closed = true
+			close(b.done)
+		}
+		b.closeMu.Unlock()
+
+		b.waitGroup.Wait()
+		close(stopped)
+	}()
+
+	defer b.cancel()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return &ShutdownError{Unflushed: int(b.pending.Load()), Err: ctx.Err()}
 	}
 }
 
 // Close stops the batch processor, flushes any remaining items, and cleans up resources.
-// It blocks until all pending operations are complete.
+// It blocks until all pending operations, including in-flight flushes, are complete.
 func (b *Batcher[T]) Close() {
-	b.cancel()
-	b.waitGroup.Wait()
-	b.ticker.Stop()
+	_ = b.Shutdown(context.Background())
 }
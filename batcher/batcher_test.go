@@ -2,11 +2,15 @@ package batcher_test
 
 import (
 	"context"
+	"errors"
+	"reflect"
+	"slices"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/Zamony/go/batcher"
+	"github.com/Zamony/go/batcher/batchertest"
 )
 
 func TestBatchByCount(t *testing.T) {
@@ -93,4 +97,481 @@ func TestClose(t *testing.T) {
 	if !flushed {
 		t.Error("Close didn't trigger flush")
 	}
+	if err := b.Add(context.Background(), 2); err != batcher.ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestRetryAndDeadLetter(t *testing.T) {
+	errFlush := errors.New("flush failed")
+	var attempts int
+	flushFunc := func(_ context.Context, items []int) error {
+		attempts++
+		return errFlush
+	}
+
+	var deadItems []int
+	var deadErr error
+	b := batcher.NewWithError(2, time.Hour, flushFunc,
+		batcher.WithRetry[int](batcher.RetryPolicy{
+			Attempts:   3,
+			MinBackoff: time.Millisecond,
+			MaxBackoff: 2 * time.Millisecond,
+			Jitter:     0.5,
+		}),
+		batcher.WithDeadLetter(func(_ context.Context, items []int, err error) {
+			deadItems, deadErr = items, err
+		}),
+	)
+	if err := b.Add(context.Background(), 1, 2); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	b.Close()
+
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+	if !reflect.DeepEqual(deadItems, []int{1, 2}) || deadErr != errFlush {
+		t.Errorf("Expected dead letter with [1 2] and %v, got %v and %v", errFlush, deadItems, deadErr)
+	}
+}
+
+func TestRetrySucceeds(t *testing.T) {
+	var attempts int
+	flushFunc := func(_ context.Context, items []int) error {
+		attempts++
+		if attempts < 2 {
+			return errors.New("flush failed")
+		}
+		return nil
+	}
+
+	b := batcher.NewWithError(1, time.Hour, flushFunc,
+		batcher.WithRetry[int](batcher.RetryPolicy{Attempts: 5, MinBackoff: time.Millisecond}),
+		batcher.WithDeadLetter(func(context.Context, []int, error) {
+			t.Error("dead letter should not be called")
+		}),
+	)
+	if err := b.Add(context.Background(), 1); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	b.Close()
+
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestFlushWorkers(t *testing.T) {
+	var mu sync.Mutex
+	var flushed []int
+	started := make(chan struct{})
+	release := make(chan struct{})
+	flushFunc := func(_ context.Context, items []int) {
+		started <- struct{}{}
+		<-release
+		mu.Lock()
+		flushed = append(flushed, items...)
+		mu.Unlock()
+	}
+
+	b := batcher.New(1, time.Hour, flushFunc, batcher.WithFlushWorkers[int](2, 2))
+	for i := range 2 {
+		if err := b.Add(context.Background(), i); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	<-started // both batches are flushed concurrently
+	<-started
+
+	// The run loop accepts one more item, which stays in the current batch
+	// until a worker is free, since maxInFlight batches are already flushed
+	if err := b.Add(context.Background(), 2); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := b.Add(ctx, 3); err != context.DeadlineExceeded {
+		t.Fatalf("Expected context.DeadlineExceeded error, got %v", err)
+	}
+
+	close(release)
+	<-started
+	b.Close()
+
+	slices.Sort(flushed)
+	if !reflect.DeepEqual(flushed, []int{0, 1, 2}) {
+		t.Errorf("Expected [0 1 2] to be flushed, got %v", flushed)
+	}
+}
+
+func TestBatchByWeight(t *testing.T) {
+	var batches [][]string
+	flushFunc := func(_ context.Context, items []string) {
+		batches = append(batches, items)
+	}
+
+	weigh := func(s string) int { return len(s) }
+	b := batcher.New(100, time.Hour, flushFunc, batcher.WithMaxWeight(weigh, 5))
+	for _, item := range []string{"ab", "cd", "ef", "oversized", "g", "hijk"} {
+		if err := b.Add(context.Background(), item); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	b.Close()
+
+	want := [][]string{{"ab", "cd"}, {"ef"}, {"oversized"}, {"g", "hijk"}}
+	if !reflect.DeepEqual(batches, want) {
+		t.Errorf("Expected %v, got %v", want, batches)
+	}
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	testCases := []struct {
+		TestName string
+		Policy   batcher.OverflowPolicy
+		Error    error
+		Flushed  []int
+	}{
+		{
+			TestName: "Drop newest",
+			Policy:   batcher.OverflowDropNewest,
+			Error:    nil,
+			Flushed:  []int{1, 2},
+		},
+		{
+			TestName: "Drop oldest",
+			Policy:   batcher.OverflowDropOldest,
+			Error:    nil,
+			Flushed:  []int{1, 3},
+		},
+		{
+			TestName: "Reject",
+			Policy:   batcher.OverflowReject,
+			Error:    batcher.ErrFull,
+			Flushed:  []int{1, 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.TestName, func(t *testing.T) {
+			var flushed []int
+			started := make(chan struct{}, 3)
+			release := make(chan struct{})
+			flushFunc := func(_ context.Context, items []int) {
+				started <- struct{}{}
+				<-release
+				flushed = append(flushed, items...)
+			}
+
+			b := batcher.New(1, time.Hour, flushFunc, batcher.WithQueue[int](1, tc.Policy))
+			if err := b.Add(context.Background(), 1); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+			<-started // the flush is stuck, next items stay in the queue
+			if err := b.Add(context.Background(), 2); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+			if err := b.Add(context.Background(), 3); err != tc.Error {
+				t.Fatalf("Expected %v error, got %v", tc.Error, err)
+			}
+
+			close(release)
+			b.Close()
+			if !reflect.DeepEqual(flushed, tc.Flushed) {
+				t.Errorf("Expected %v to be flushed, got %v", tc.Flushed, flushed)
+			}
+		})
+	}
+}
+
+func TestCloseBlockedAdd(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	flushFunc := func(_ context.Context, items []int) {
+		started <- struct{}{}
+		<-release
+	}
+
+	b := batcher.New(1, time.Hour, flushFunc)
+	if err := b.Add(context.Background(), 1); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	<-started // the flush is stuck, next Add blocks
+
+	added := make(chan error)
+	go func() {
+		added <- b.Add(context.Background(), 2)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		b.Close()
+		close(closed)
+	}()
+	if err := <-added; err != batcher.ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+
+	close(release)
+	<-closed
+}
+
+func TestFlush(t *testing.T) {
+	errFlush := errors.New("flush failed")
+	var batches [][]int
+	flushFunc := func(_ context.Context, items []int) error {
+		batches = append(batches, items)
+		return errFlush
+	}
+
+	b := batcher.NewWithError(100, time.Hour, flushFunc)
+	if err := b.Add(context.Background(), 1, 2); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := b.Flush(context.Background()); !errors.Is(err, errFlush) {
+		t.Fatalf("Expected %v error, got %v", errFlush, err)
+	}
+	if !reflect.DeepEqual(batches, [][]int{{1, 2}}) {
+		t.Errorf("Expected [[1 2]], got %v", batches)
+	}
+
+	b.Close()
+	if err := b.Flush(context.Background()); err != batcher.ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestFlushWaitsForWorkers(t *testing.T) {
+	var mu sync.Mutex
+	var flushed []int
+	flushFunc := func(_ context.Context, items []int) {
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		flushed = append(flushed, items...)
+		mu.Unlock()
+	}
+
+	b := batcher.New(1, time.Hour, flushFunc, batcher.WithFlushWorkers[int](2, 4))
+	defer b.Close()
+	for i := range 3 {
+		if err := b.Add(context.Background(), i); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(flushed) != 3 {
+		t.Errorf("Expected 3 flushed items, got %v", flushed)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	canceled := make(chan struct{})
+	flushFunc := func(ctx context.Context, items []int) {
+		<-ctx.Done()
+		close(canceled)
+	}
+
+	b := batcher.New(100, time.Hour, flushFunc)
+	if err := b.Add(context.Background(), 1, 2, 3); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := b.Shutdown(ctx)
+	var shutdownErr *batcher.ShutdownError
+	if !errors.As(err, &shutdownErr) {
+		t.Fatalf("Expected ShutdownError, got %v", err)
+	}
+	if shutdownErr.Unflushed != 3 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected 3 unflushed items and deadline error, got %v", err)
+	}
+	<-canceled
+}
+
+func TestShutdownDeadlineBlockedAdd(t *testing.T) {
+	started := make(chan struct{}, 1)
+	flushFunc := func(ctx context.Context, items []int) {
+		started <- struct{}{}
+		<-ctx.Done()
+	}
+
+	b := batcher.New(1, time.Hour, flushFunc)
+	if err := b.Add(context.Background(), 1); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	<-started // the flush hangs, next Add blocks
+
+	added := make(chan error, 1)
+	go func() {
+		added <- b.Add(context.Background(), 2)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- b.Shutdown(ctx)
+	}()
+
+	select {
+	case err := <-shutdown:
+		var shutdownErr *batcher.ShutdownError
+		if !errors.As(err, &shutdownErr) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected ShutdownError with deadline error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Shutdown didn't return at the deadline")
+	}
+	if err := <-added; err != batcher.ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestAddWait(t *testing.T) {
+	errFlush := errors.New("flush failed")
+	flushFunc := func(_ context.Context, items []string) error {
+		if slices.Contains(items, "bad") {
+			return errFlush
+		}
+		return nil
+	}
+
+	weigh := func(s string) int { return len(s) }
+	b := batcher.NewWithError(100, time.Hour, flushFunc, batcher.WithMaxWeight(weigh, 4))
+	defer b.Close()
+
+	good, err := b.AddWait(context.Background(), "ab", "cd")
+	if err != nil {
+		t.Fatalf("AddWait failed: %v", err)
+	}
+	if err := good.Wait(context.Background()); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	// The items are split into two batches, one of them fails
+	bad, err := b.AddWait(context.Background(), "ef", "bad")
+	if err != nil {
+		t.Fatalf("AddWait failed: %v", err)
+	}
+	if err := b.Flush(context.Background()); !errors.Is(err, errFlush) {
+		t.Fatalf("Expected %v error, got %v", errFlush, err)
+	}
+	<-bad.Done()
+	if err := bad.Err(); err != errFlush {
+		t.Errorf("Expected %v error, got %v", errFlush, err)
+	}
+}
+
+func TestAddWaitDropped(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	flushFunc := func(_ context.Context, items []int) {
+		started <- struct{}{}
+		<-release
+	}
+
+	b := batcher.New(1, time.Hour, flushFunc, batcher.WithQueue[int](1, batcher.OverflowDropOldest))
+	if err := b.Add(context.Background(), 1); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	<-started
+	oldest, err := b.AddWait(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("AddWait failed: %v", err)
+	}
+	newest, err := b.AddWait(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("AddWait failed: %v", err)
+	}
+	if err := oldest.Wait(context.Background()); err != batcher.ErrDropped {
+		t.Errorf("Expected ErrDropped, got %v", err)
+	}
+
+	close(release)
+	b.Close()
+	if err := newest.Wait(context.Background()); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestBatchByTimeoutWithClock(t *testing.T) {
+	flushed := make(chan []int, 1)
+	flushFunc := func(_ context.Context, items []int) {
+		flushed <- items
+	}
+
+	clock := batchertest.NewClock(time.Now())
+	b := batcher.New(100, time.Second, flushFunc, batcher.WithClock[int](clock))
+	defer b.Close()
+	if err := b.Add(context.Background(), 1); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	clock.Advance(time.Second)
+	if items := <-flushed; !reflect.DeepEqual(items, []int{1}) {
+		t.Errorf("Expected [1], got %v", items)
+	}
+}
+
+func TestRetryBackoffWithClock(t *testing.T) {
+	attempts := make(chan struct{}, 3)
+	flushFunc := func(_ context.Context, items []int) error {
+		attempts <- struct{}{}
+		return errors.New("flush failed")
+	}
+
+	clock := batchertest.NewClock(time.Now())
+	b := batcher.NewWithError(1, time.Hour, flushFunc,
+		batcher.WithClock[int](clock),
+		batcher.WithRetry[int](batcher.RetryPolicy{Attempts: 3, MinBackoff: time.Minute}),
+	)
+	defer b.Close()
+	if err := b.Add(context.Background(), 1); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	<-attempts
+	clock.BlockUntil(2) // the ticker and the backoff timer
+	clock.Advance(time.Minute)
+	<-attempts
+	clock.BlockUntil(2)
+	clock.Advance(time.Minute)
+	select {
+	case <-attempts:
+		t.Fatal("The second backoff must be longer")
+	default:
+	}
+	clock.Advance(time.Minute)
+	<-attempts
+}
+
+func TestRetryZeroBackoffWithClock(t *testing.T) {
+	var attempts int
+	flushFunc := func(_ context.Context, items []int) error {
+		attempts++
+		return errors.New("flush failed")
+	}
+
+	clock := batchertest.NewClock(time.Now())
+	b := batcher.NewWithError(1, time.Hour, flushFunc,
+		batcher.WithClock[int](clock),
+		batcher.WithRetry[int](batcher.RetryPolicy{Attempts: 3}),
+	)
+	if err := b.Add(context.Background(), 1); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	b.Close() // retries don't wait for the clock
+
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
 }
@@ -0,0 +1,157 @@
+// Package batchertest provides utilities for testing code that uses batcher.
+package batchertest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Zamony/go/batcher"
+)
+
+// Clock is a batcher.Clock, which time is advanced manually.
+// It is goroutine-safe.
+type Clock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time // protected by mu
+	waiters []*waiter // protected by mu
+}
+
+// waiter is a pending timer or an active ticker.
+type waiter struct {
+	clock  *Clock
+	ch     chan time.Time
+	next   time.Time     // protected by clock.mu
+	period time.Duration // protected by clock.mu, zero for timers
+}
+
+// NewClock creates a new clock set to the given time.
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker creates a ticker, which ticks when the clock is advanced.
+func (c *Clock) NewTicker(d time.Duration) batcher.Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return c.add(d, d)
+}
+
+// After returns a channel, which receives the time once the clock is advanced by d.
+// Like time.After, the channel receives the time immediately if d is not positive.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	if d <= 0 {
+		ch := make(chan time.Time, 1)
+		ch <- c.Now()
+		return ch
+	}
+	return c.add(d, 0).ch
+}
+
+func (c *Clock) add(d, period time.Duration) *waiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &waiter{clock: c, ch: make(chan time.Time, 1), next: c.now.Add(d), period: period}
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+	return w
+}
+
+// Advance moves the clock forward firing due timers and tickers in order.
+// Like time.Ticker, a ticker drops ticks if the receiver falls behind.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+	for {
+		w := c.earliest()
+		if w == nil || w.next.After(end) {
+			break
+		}
+
+		c.now = w.next
+		select {
+		case w.ch <- c.now:
+		default:
+		}
+		if w.period > 0 {
+			w.next = w.next.Add(w.period)
+		} else {
+			c.remove(w)
+		}
+	}
+	c.now = end
+}
+
+// BlockUntil blocks until there are n pending timers and tickers,
+// e.g. until the code under test starts waiting on the clock.
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *Clock) earliest() *waiter {
+	var first *waiter
+	for _, w := range c.waiters {
+		if first == nil || w.next.Before(first.next) {
+			first = w
+		}
+	}
+	return first
+}
+
+func (c *Clock) remove(w *waiter) {
+	for i := range c.waiters {
+		if c.waiters[i] == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			break
+		}
+	}
+	c.cond.Broadcast()
+}
+
+// C returns the channel on which the ticks are delivered.
+func (w *waiter) C() <-chan time.Time {
+	return w.ch
+}
+
+// Reset stops the ticker and resets its period to the specified duration.
+func (w *waiter) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for Ticker.Reset")
+	}
+	c := w.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w.next = c.now.Add(d)
+	w.period = d
+	for _, other := range c.waiters {
+		if other == w {
+			return
+		}
+	}
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+}
+
+// Stop turns off the ticker.
+func (w *waiter) Stop() {
+	c := w.clock
+	c.mu.Lock()
+	c.remove(w)
+	c.mu.Unlock()
+}
@@ -0,0 +1,67 @@
+package batchertest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Zamony/go/batcher/batchertest"
+)
+
+func TestClockTicker(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := batchertest.NewClock(start)
+	ticker := clock.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	clock.Advance(999 * time.Millisecond)
+	select {
+	case <-ticker.C():
+		t.Fatal("Unexpected tick")
+	default:
+	}
+
+	clock.Advance(time.Millisecond)
+	if got, want := <-ticker.C(), start.Add(time.Second); !got.Equal(want) {
+		t.Errorf("Expected tick at %v, got %v", want, got)
+	}
+
+	ticker.Reset(time.Minute)
+	clock.Advance(time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("Unexpected tick after reset")
+	default:
+	}
+}
+
+func TestClockAfter(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := batchertest.NewClock(start)
+	go func() {
+		clock.BlockUntil(1)
+		clock.Advance(time.Hour)
+	}()
+
+	if got, want := <-clock.After(time.Minute), start.Add(time.Minute); !got.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got, want := clock.Now(), start.Add(time.Hour); !got.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestClockAfterNonPositive(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := batchertest.NewClock(start)
+
+	for _, d := range []time.Duration{0, -time.Second} {
+		select {
+		case got := <-clock.After(d):
+			if !got.Equal(start) {
+				t.Errorf("Expected %v, got %v", start, got)
+			}
+		default:
+			t.Errorf("Expected After(%v) to fire immediately", d)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package batcher
+
+import "context"
+
+// Option configures a Batcher.
+type Option[T any] func(*config[T])
+
+type config[T any] struct {
+	Retry       RetryPolicy
+	DeadLetter  DeadLetterFunc[T]
+	Workers     int
+	MaxInFlight int
+	Weigh       WeighFunc[T]
+	MaxWeight   int
+	QueueSize   int
+	Overflow    OverflowPolicy
+	Hooks       Hooks
+	Clock       Clock
+	Spool       *Spool[T]
+}
+
+func newConfig[T any](options []Option[T]) config[T] {
+	cfg := config[T]{Clock: realClock{}}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// DeadLetterFunc receives a batch which couldn't be flushed
+// along with the error returned by the last flush attempt.
+type DeadLetterFunc[T any] func(context.Context, []T, error)
+
+// WeighFunc returns the weight of an item, e.g. its size in bytes.
+type WeighFunc[T any] func(T) int
+
+// OverflowPolicy defines the behaviour of Add when the queue of the Batcher is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes Add wait until there is room in the queue.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest makes Add silently discard the added items.
+	OverflowDropNewest
+	// OverflowDropOldest makes Add discard the oldest queued items to make room for the added ones.
+	OverflowDropOldest
+	// OverflowReject makes Add return ErrFull.
+	OverflowReject
+)
+
+// WithRetry sets the policy of retrying failed flushes.
+// By default failed flushes are not retried.
+func WithRetry[T any](policy RetryPolicy) Option[T] {
+	return func(c *config[T]) {
+		c.Retry = policy
+	}
+}
+
+// WithDeadLetter sets the function, which is called
+// when a batch couldn't be flushed after all retries.
+// By default such batches are dropped.
+func WithDeadLetter[T any](fun DeadLetterFunc[T]) Option[T] {
+	return func(c *config[T]) {
+		c.DeadLetter = fun
+	}
+}
+
+// WithFlushWorkers makes the Batcher flush batches in the background
+// using the given number of goroutines, so new items are accepted while
+// previous batches are being flushed. The flush function is called concurrently.
+// maxInFlight limits the number of batches being flushed or waiting for a worker,
+// it can't be less than the number of workers. When the limit is reached,
+// the items stay in the current batch and Add blocks.
+// By default batches are flushed synchronously.
+func WithFlushWorkers[T any](workers, maxInFlight int) Option[T] {
+	return func(c *config[T]) {
+		c.Workers = workers
+		c.MaxInFlight = max(maxInFlight, workers)
+	}
+}
+
+// WithMaxWeight makes the Batcher flush a batch when the total weight of its items
+// reaches maxWeight. The batch is flushed before adding an item which doesn't fit,
+// so batches never exceed maxWeight unless they consist of a single item.
+// The item count limit is still applied.
+func WithMaxWeight[T any](weigh WeighFunc[T], maxWeight int) Option[T] {
+	return func(c *config[T]) {
+		c.Weigh = weigh
+		c.MaxWeight = maxWeight
+	}
+}
+
+// WithQueue sets the size of the queue, which holds added items
+// until the Batcher appends them to a batch, and the policy of handling its overflow.
+// The size is counted in Add calls rather than items.
+// The queue size is at least one for policies other than OverflowBlock.
+// By default there is no queue and Add blocks until the items are appended to a batch.
+func WithQueue[T any](size int, policy OverflowPolicy) Option[T] {
+	return func(c *config[T]) {
+		c.QueueSize = max(size, 0)
+		if policy != OverflowBlock {
+			c.QueueSize = max(size, 1)
+		}
+		c.Overflow = policy
+	}
+}
+
+// WithHooks sets the receiver of the Batcher events, e.g. to export metrics.
+func WithHooks[T any](hooks Hooks) Option[T] {
+	return func(c *config[T]) {
+		c.Hooks = hooks
+	}
+}
+
+// WithClock sets the source of time for timeouts, retries and statistics.
+// It is useful for testing, see batchertest.Clock.
+func WithClock[T any](clock Clock) Option[T] {
+	return func(c *config[T]) {
+		c.Clock = clock
+	}
+}
+
+// WithSpool makes the Batcher persist added items in the spool until they are flushed.
+// Items left in the spool by a previous run are flushed on start.
+// Items of the batches, which failed without a dead letter function, are kept in the spool.
+// The spool must not be shared between batchers, so it can't be used with Partitioned.
+func WithSpool[T any](spool *Spool[T]) Option[T] {
+	return func(c *config[T]) {
+		c.Spool = spool
+	}
+}
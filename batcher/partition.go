@@ -0,0 +1,181 @@
+package batcher
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// KeyedFlushFunc defines the signature for the function that will be called
+// when the batch of a partition is flushed. It receives the partition key along with the items.
+type KeyedFlushFunc[K comparable, T any] func(context.Context, K, []T) error
+
+// Partitioned batches items separately for every key.
+// Each partition has its own batch with maxCount and timeout semantics of the Batcher.
+// Partitions are created on demand and evicted after a period of inactivity.
+type Partitioned[K comparable, T any] struct {
+	mu          sync.Mutex
+	partitions  map[K]*partition[T] // protected by mu
+	closed      bool                // protected by mu
+	maxCount    int
+	timeout     time.Duration
+	idleTimeout time.Duration
+	flushFunc   KeyedFlushFunc[K, T]
+	options     []Option[T]
+	clock       Clock
+	done        chan struct{}
+	waitGroup   sync.WaitGroup
+}
+
+type partition[T any] struct {
+	batcher  *Batcher[T]
+	active   int // number of ongoing Add calls
+	lastUsed time.Time
+}
+
+// NewPartitioned creates and starts a new Partitioned instance.
+// A partition is flushed and evicted if no items were added to it for idleTimeout.
+// The options are applied to the Batcher of every partition.
+// See New for the description of the other arguments.
+func NewPartitioned[K comparable, T any](
+	maxCount int, timeout, idleTimeout time.Duration, fun KeyedFlushFunc[K, T], options ...Option[T],
+) *Partitioned[K, T] {
+	p := &Partitioned[K, T]{
+		partitions:  make(map[K]*partition[T]),
+		maxCount:    maxCount,
+		timeout:     timeout,
+		idleTimeout: idleTimeout,
+		flushFunc:   fun,
+		options:     options,
+		clock:       newConfig(options).Clock,
+		done:        make(chan struct{}),
+	}
+
+	p.waitGroup.Add(1)
+	go func() {
+		defer p.waitGroup.Done()
+		p.run()
+	}()
+
+	return p
+}
+
+// Add adds one or more items to the batch of the given key.
+// Returns an error if the context is canceled before the items can be added,
+// or ErrClosed if the Partitioned is closed.
+func (p *Partitioned[K, T]) Add(ctx context.Context, key K, items ...T) error {
+	part, err := p.acquire(key)
+	if err != nil {
+		return err
+	}
+	defer p.release(part)
+	return part.batcher.Add(ctx, items...)
+}
+
+// AddWait is like Add, but it also returns a Result,
+// which is resolved when the items are flushed.
+func (p *Partitioned[K, T]) AddWait(ctx context.Context, key K, items ...T) (*Result, error) {
+	part, err := p.acquire(key)
+	if err != nil {
+		return nil, err
+	}
+	defer p.release(part)
+	return part.batcher.AddWait(ctx, items...)
+}
+
+// Len returns the number of partitions.
+func (p *Partitioned[K, T]) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.partitions)
+}
+
+// acquire returns the partition of the given key, creating it if necessary.
+// The partition isn't evicted until it is released.
+func (p *Partitioned[K, T]) acquire(key K) (*partition[T], error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, ErrClosed
+	}
+
+	part, ok := p.partitions[key]
+	if !ok {
+		part = &partition[T]{
+			batcher: NewWithError(p.maxCount, p.timeout, func(ctx context.Context, items []T) error {
+				return p.flushFunc(ctx, key, items)
+			}, p.options...),
+		}
+		p.partitions[key] = part
+	}
+	part.active++
+	return part, nil
+}
+
+func (p *Partitioned[K, T]) release(part *partition[T]) {
+	p.mu.Lock()
+	part.active--
+	part.lastUsed = p.clock.Now()
+	p.mu.Unlock()
+}
+
+// run periodically evicts idle partitions.
+func (p *Partitioned[K, T]) run() {
+	ticker := p.clock.NewTicker(p.idleTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C():
+			closeAll(p.evict(now))
+		}
+	}
+}
+
+// evict removes idle partitions and returns their batchers.
+func (p *Partitioned[K, T]) evict(now time.Time) []*Batcher[T] {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var idle []*Batcher[T]
+	for key, part := range p.partitions {
+		if part.active == 0 && now.Sub(part.lastUsed) >= p.idleTimeout {
+			idle = append(idle, part.batcher)
+			delete(p.partitions, key)
+		}
+	}
+	return idle
+}
+
+// Close stops all partitions and flushes their remaining items.
+// It blocks until all pending operations are complete.
+func (p *Partitioned[K, T]) Close() {
+	p.mu.Lock()
+	p.closed = true
+	partitions := p.partitions
+	p.partitions = nil
+	p.mu.Unlock()
+
+	close(p.done)
+	p.waitGroup.Wait()
+
+	batchers := make([]*Batcher[T], 0, len(partitions))
+	for _, part := range partitions {
+		batchers = append(batchers, part.batcher)
+	}
+	closeAll(batchers)
+}
+
+// closeAll closes the batchers concurrently.
+func closeAll[T any](batchers []*Batcher[T]) {
+	var wg sync.WaitGroup
+	for _, b := range batchers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.Close()
+		}()
+	}
+	wg.Wait()
+}
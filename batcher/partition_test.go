@@ -0,0 +1,69 @@
+package batcher_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Zamony/go/batcher"
+)
+
+func TestPartitionedByKey(t *testing.T) {
+	var mu sync.Mutex
+	batches := make(map[string][][]int)
+	flushFunc := func(_ context.Context, key string, items []int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		batches[key] = append(batches[key], items)
+		return nil
+	}
+
+	p := batcher.NewPartitioned(2, time.Hour, time.Hour, flushFunc)
+	for i := range 3 {
+		if err := p.Add(context.Background(), "a", i); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		if err := p.Add(context.Background(), "b", i*10); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if n := p.Len(); n != 2 {
+		t.Errorf("Expected 2 partitions, got %d", n)
+	}
+	p.Close()
+
+	want := map[string][][]int{
+		"a": {{0, 1}, {2}},
+		"b": {{0, 10}, {20}},
+	}
+	if !reflect.DeepEqual(batches, want) {
+		t.Errorf("Expected %v, got %v", want, batches)
+	}
+
+	if err := p.Add(context.Background(), "a", 1); err != batcher.ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestPartitionedEviction(t *testing.T) {
+	flushed := make(chan []int, 1)
+	flushFunc := func(_ context.Context, key string, items []int) error {
+		flushed <- items
+		return nil
+	}
+
+	p := batcher.NewPartitioned(100, time.Hour, 10*time.Millisecond, flushFunc)
+	defer p.Close()
+	if err := p.Add(context.Background(), "a", 1); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	if items := <-flushed; !reflect.DeepEqual(items, []int{1}) {
+		t.Errorf("Expected [1] to be flushed on eviction, got %v", items)
+	}
+	for p.Len() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
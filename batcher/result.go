@@ -0,0 +1,65 @@
+package batcher
+
+import (
+	"context"
+	"sync"
+)
+
+// Result is a handle of the items added with AddWait.
+// It is resolved when all batches containing the items are flushed.
+type Result struct {
+	mu      sync.Mutex
+	pending int   // protected by mu
+	err     error // protected by mu
+	done    chan struct{}
+}
+
+func newResult() *Result {
+	// The result is held until all items are appended to batches.
+	return &Result{pending: 1, done: make(chan struct{})}
+}
+
+// Done returns a channel that's closed when the result is resolved.
+func (r *Result) Done() <-chan struct{} {
+	return r.done
+}
+
+// Err returns the first error of the batches containing the items,
+// or ErrDropped if the items were discarded due to the queue overflow.
+// It returns nil until the result is resolved.
+func (r *Result) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Wait waits until the result is resolved and returns its error.
+// A context error is returned on canceled context.
+func (r *Result) Wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.done:
+		return r.Err()
+	}
+}
+
+// hold marks the result as waiting for one more batch.
+func (r *Result) hold() {
+	r.mu.Lock()
+	r.pending++
+	r.mu.Unlock()
+}
+
+// release marks one of the awaited batches as complete.
+func (r *Result) release(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+	r.pending--
+	if r.pending == 0 {
+		close(r.done)
+	}
+}
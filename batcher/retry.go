@@ -0,0 +1,64 @@
+package batcher
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy describes how failed flushes are retried.
+// The delay between attempts grows exponentially starting from MinBackoff
+// and doubling after every failed attempt until it reaches MaxBackoff.
+// The zero value disables retries.
+type RetryPolicy struct {
+	Attempts   int           // Maximum number of attempts including the first one.
+	MinBackoff time.Duration // Delay before the first retry.
+	MaxBackoff time.Duration // Maximum delay between attempts, zero means no limit.
+	Jitter     float64       // Fraction of the delay which is randomized, from 0 to 1.
+}
+
+// do calls fun until it succeeds or the attempts are exhausted.
+// Returns the error of the last attempt.
+// Backoff is interrupted if the context is canceled.
+func (p RetryPolicy) do(ctx context.Context, clock Clock, fun func() error) error {
+	err := fun()
+	for attempt := 1; err != nil && attempt < p.Attempts; attempt++ {
+		if !sleep(ctx, clock, p.backoff(attempt)) {
+			return err
+		}
+		err = fun()
+	}
+	return err
+}
+
+// backoff returns the delay before the given retry attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MinBackoff
+	for i := 1; i < attempt && delay <= math.MaxInt64/2; i++ {
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			break
+		}
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+
+	jitter := min(max(p.Jitter, 0), 1)
+	if spread := int64(float64(delay) * jitter); spread > 0 {
+		delay -= time.Duration(rand.Int64N(spread + 1))
+	}
+	return delay
+}
+
+// sleep pauses for the given duration.
+// Returns false if the context was canceled earlier.
+func sleep(ctx context.Context, clock Clock, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-clock.After(d):
+		return true
+	}
+}
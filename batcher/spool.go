@@ -0,0 +1,304 @@
+package batcher
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Codec encodes and decodes items stored in a Spool.
+// Encoded items must not be empty.
+type Codec[T any] interface {
+	Encode(T) ([]byte, error)
+	Decode([]byte) (T, error)
+}
+
+// JSONCodec is a Codec, which encodes items as JSON.
+type JSONCodec[T any] struct{}
+
+// Encode encodes the item as JSON.
+func (JSONCodec[T]) Encode(item T) ([]byte, error) {
+	return json.Marshal(item)
+}
+
+// Decode decodes the item from JSON.
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var item T
+	err := json.Unmarshal(data, &item)
+	return item, err
+}
+
+// SpoolOptions holds configuration options for the Spool.
+type SpoolOptions struct {
+	SegmentSize int64 // Size of a segment file after which a new segment is started.
+	Sync        bool  // Sync every write to survive power loss, not just a process crash.
+}
+
+// defaultSpoolOptions provides default configuration options.
+var defaultSpoolOptions = &SpoolOptions{
+	SegmentSize: 16 << 20,
+}
+
+// parseSpoolOptions merges provided options with defaults.
+func parseSpoolOptions(opts *SpoolOptions) *SpoolOptions {
+	if opts == nil {
+		return defaultSpoolOptions
+	}
+	o := *defaultSpoolOptions
+	if opts.SegmentSize > 0 {
+		o.SegmentSize = opts.SegmentSize
+	}
+	o.Sync = opts.Sync
+	return &o
+}
+
+const (
+	segmentExt   = ".seg"
+	recordHeader = 8 // length and checksum
+)
+
+// Spool is a write-ahead log of the added items.
+// It consists of append-only segment files, which are deleted
+// (or truncated, if the segment is still written to) once all their items
+// are flushed. All items of a segment with unflushed items are replayed
+// by the Batcher on start, so the flushed items of such a segment
+// are flushed twice.
+//
+// Spool is goroutine-safe.
+type Spool[T any] struct {
+	mu        sync.Mutex
+	dir       string
+	codec     Codec[T]
+	options   *SpoolOptions
+	active    *segment  // protected by mu
+	file      *os.File  // file of the active segment, protected by mu
+	recovered []item[T] // protected by mu
+}
+
+// segment is a spool file.
+type segment struct {
+	id      uint64
+	path    string
+	size    int64
+	pending int  // number of items, which are not flushed yet
+	sealed  bool // no more items are written to the segment
+}
+
+// item is an item replayed from a spool.
+type item[T any] struct {
+	value T
+	ref   *segment
+}
+
+// OpenSpool opens the spool in the given directory creating it if necessary.
+// Items left by a previous run are read into memory to be replayed by the Batcher.
+func OpenSpool[T any](dir string, codec Codec[T], options *SpoolOptions) (*Spool[T], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create spool dir: %w", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		return nil, fmt.Errorf("list segments: %w", err)
+	}
+	slices.Sort(paths) // names are zero-padded ids
+
+	s := &Spool[T]{dir: dir, codec: codec, options: parseSpoolOptions(options)}
+	var lastID uint64
+	for _, path := range paths {
+		id, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), segmentExt), 10, 64)
+		if err != nil {
+			continue // not a segment
+		}
+		lastID = max(lastID, id)
+		if err := s.recover(path); err != nil {
+			return nil, fmt.Errorf("recover segment %s: %w", path, err)
+		}
+	}
+
+	if err := s.rotate(lastID + 1); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// recover reads the items of the segment.
+// A partially written record at the end of the segment is ignored,
+// as well as the rest of the segment after it, e.g. a zero-filled tail
+// left by a power loss.
+func (s *Spool[T]) recover(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	seg := &segment{path: path, sealed: true}
+	reader := bufio.NewReader(file)
+	header := make([]byte, recordHeader)
+	for remaining := info.Size(); ; {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			break // end of the segment or a torn write
+		}
+		remaining -= recordHeader
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		if size == 0 || size > remaining {
+			break // records are never empty, so the header is torn
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			break
+		}
+		remaining -= size
+		if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:]) {
+			break
+		}
+
+		value, err := s.codec.Decode(data)
+		if err != nil {
+			break // the checksum matched garbage
+		}
+		s.recovered = append(s.recovered, item[T]{value, seg})
+		seg.pending++
+	}
+
+	if seg.pending == 0 {
+		return os.Remove(path)
+	}
+	return nil
+}
+
+// rotate seals the active segment and starts a new one.
+// Must be called with mu held.
+func (s *Spool[T]) rotate(id uint64) error {
+	if s.file != nil {
+		s.active.sealed = true
+		_ = s.file.Close()
+		s.file = nil
+		s.release(s.active)
+	}
+
+	path := filepath.Join(s.dir, fmt.Sprintf("%020d%s", id, segmentExt))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("create segment: %w", err)
+	}
+	s.active = &segment{id: id, path: path}
+	s.file = file
+	return nil
+}
+
+// append writes the items to the active segment.
+// Returns the segment, which should be released for every item once it is flushed.
+func (s *Spool[T]) append(items []T) (*segment, error) {
+	var buf []byte
+	for _, value := range items {
+		data, err := s.codec.Encode(value)
+		if err != nil {
+			return nil, fmt.Errorf("encode: %w", err)
+		}
+		if len(data) == 0 {
+			return nil, fmt.Errorf("encode: empty item")
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(data)))
+		buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(data))
+		buf = append(buf, data...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil, os.ErrClosed
+	}
+	if s.active.size >= s.options.SegmentSize {
+		if err := s.rotate(s.active.id + 1); err != nil {
+			return nil, err
+		}
+	}
+
+	seg := s.active
+	n, err := s.file.Write(buf)
+	seg.size += int64(n)
+	if err == nil && s.options.Sync {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		// Don't append after a torn record, it would be unreadable.
+		_ = s.rotate(s.active.id + 1)
+		return nil, fmt.Errorf("write segment: %w", err)
+	}
+
+	seg.pending += len(items)
+	return seg, nil
+}
+
+// replay returns the recovered items once.
+func (s *Spool[T]) replay() []item[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := s.recovered
+	s.recovered = nil
+	return items
+}
+
+// ack marks the items of the segments as flushed.
+// Segments are deleted or truncated when all their items are flushed.
+func (s *Spool[T]) ack(refs []*segment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, seg := range refs {
+		seg.pending--
+		s.release(seg)
+	}
+}
+
+// release deletes the segment if it has no pending items.
+// The active segment is truncated instead, so it is reused from the start.
+// On failure the items are replayed again on the next start.
+// Must be called with mu held.
+func (s *Spool[T]) release(seg *segment) {
+	switch {
+	case seg.pending > 0:
+	case seg.sealed:
+		_ = os.Remove(seg.path)
+	case seg == s.active && seg.size > 0:
+		if err := s.file.Truncate(0); err != nil {
+			return
+		}
+		seg.size = 0
+		if s.options.Sync {
+			_ = s.file.Sync()
+		}
+	}
+}
+
+// Close closes the spool. Items, which weren't flushed, remain on disk.
+func (s *Spool[T]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+
+	s.active.sealed = true
+	err := s.file.Close()
+	s.file = nil
+	s.release(s.active)
+	if err != nil {
+		return fmt.Errorf("close segment: %w", err)
+	}
+	return nil
+}
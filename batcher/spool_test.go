@@ -0,0 +1,239 @@
+package batcher_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Zamony/go/batcher"
+)
+
+func openSpool(t *testing.T, dir string, options *batcher.SpoolOptions) *batcher.Spool[string] {
+	t.Helper()
+	spool, err := batcher.OpenSpool(dir, batcher.JSONCodec[string]{}, options)
+	if err != nil {
+		t.Fatalf("OpenSpool failed: %v", err)
+	}
+	return spool
+}
+
+func segments(t *testing.T, dir string) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if err != nil {
+		t.Fatalf("Glob failed: %v", err)
+	}
+	return paths
+}
+
+func TestSpoolReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	// The sink is down, so the items stay in the spool
+	spool := openSpool(t, dir, nil)
+	failing := func(context.Context, []string) error {
+		return errors.New("flush failed")
+	}
+	b := batcher.NewWithError(100, time.Hour, failing, batcher.WithSpool(spool))
+	if err := b.Add(context.Background(), "a", "b"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := b.Add(context.Background(), "c"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	b.Close()
+	if err := spool.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Simulate a torn write at the end of the segment
+	paths := segments(t, dir)
+	if len(paths) != 1 {
+		t.Fatalf("Expected 1 segment, got %v", paths)
+	}
+	file, err := os.OpenFile(paths[0], os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	if _, err := file.Write([]byte{0, 0, 0, 9, 1}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	file.Close()
+
+	var flushed [][]string
+	spool = openSpool(t, dir, nil)
+	b = batcher.New(100, time.Hour, func(_ context.Context, items []string) {
+		flushed = append(flushed, items)
+	}, batcher.WithSpool(spool))
+	if err := b.Add(context.Background(), "d"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	b.Close()
+	if err := spool.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	want := [][]string{{"a", "b", "c", "d"}}
+	if !reflect.DeepEqual(flushed, want) {
+		t.Errorf("Expected %v, got %v", want, flushed)
+	}
+	if paths := segments(t, dir); len(paths) != 0 {
+		t.Errorf("Expected segments to be deleted, got %v", paths)
+	}
+}
+
+func TestSpoolRotation(t *testing.T) {
+	dir := t.TempDir()
+	spool := openSpool(t, dir, &batcher.SpoolOptions{SegmentSize: 16, Sync: true})
+	defer spool.Close()
+
+	flushed := 0
+	b := batcher.New(2, time.Hour, func(_ context.Context, items []string) {
+		flushed += len(items)
+	}, batcher.WithSpool(spool))
+	defer b.Close()
+
+	for range 5 {
+		if err := b.Add(context.Background(), "item"); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if flushed != 5 {
+		t.Errorf("Expected 5 flushed items, got %d", flushed)
+	}
+	if paths := segments(t, dir); len(paths) != 1 { // the active one
+		t.Errorf("Expected only the active segment, got %v", paths)
+	}
+}
+
+func TestSpoolCrashAfterFlush(t *testing.T) {
+	dir := t.TempDir()
+	spool := openSpool(t, dir, nil)
+	defer spool.Close()
+
+	crashed := batcher.New(1000, time.Hour, func(context.Context, []string) {}, batcher.WithSpool(spool))
+	defer crashed.Close()
+	for range 100 {
+		if err := crashed.Add(context.Background(), "item"); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if err := crashed.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if err := crashed.Add(context.Background(), "unflushed"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	// Crash: the first spool is never closed.
+	var flushed []string
+	recovered := openSpool(t, dir, nil)
+	defer recovered.Close()
+	b := batcher.New(1000, time.Hour, func(_ context.Context, items []string) {
+		flushed = append(flushed, items...)
+	}, batcher.WithSpool(recovered))
+	b.Close()
+
+	if want := []string{"unflushed"}; !reflect.DeepEqual(flushed, want) {
+		t.Errorf("Expected %v, got %v", want, flushed)
+	}
+}
+
+func TestSpoolCorruptTail(t *testing.T) {
+	testCases := []struct {
+		TestName string
+		Tail     []byte
+	}{
+		{
+			TestName: "Zero-filled tail",
+			Tail:     make([]byte, 4096),
+		},
+		{
+			TestName: "Length beyond the segment",
+			Tail:     []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.TestName, func(t *testing.T) {
+			dir := t.TempDir()
+			spool := openSpool(t, dir, nil)
+			failing := func(context.Context, []string) error {
+				return errors.New("flush failed")
+			}
+			b := batcher.NewWithError(100, time.Hour, failing, batcher.WithSpool(spool))
+			if err := b.Add(context.Background(), "a"); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+			b.Close()
+			if err := spool.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			paths := segments(t, dir)
+			if len(paths) != 1 {
+				t.Fatalf("Expected 1 segment, got %v", paths)
+			}
+			file, err := os.OpenFile(paths[0], os.O_APPEND|os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatalf("OpenFile failed: %v", err)
+			}
+			if _, err := file.Write(tc.Tail); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			file.Close()
+
+			var flushed []string
+			spool = openSpool(t, dir, nil)
+			defer spool.Close()
+			b = batcher.New(100, time.Hour, func(_ context.Context, items []string) {
+				flushed = append(flushed, items...)
+			}, batcher.WithSpool(spool))
+			b.Close()
+
+			if want := []string{"a"}; !reflect.DeepEqual(flushed, want) {
+				t.Errorf("Expected %v, got %v", want, flushed)
+			}
+		})
+	}
+}
+
+func TestSpoolReplayMaxCount(t *testing.T) {
+	const maxCount = 3
+
+	dir := t.TempDir()
+	spool := openSpool(t, dir, nil)
+	failing := func(context.Context, []string) error {
+		return errors.New("flush failed")
+	}
+	b := batcher.NewWithError(100, time.Hour, failing, batcher.WithSpool(spool))
+	for _, item := range []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"} {
+		if err := b.Add(context.Background(), item); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	b.Close()
+	if err := spool.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	var flushed [][]string
+	spool = openSpool(t, dir, nil)
+	defer spool.Close()
+	b = batcher.New(maxCount, time.Hour, func(_ context.Context, items []string) {
+		flushed = append(flushed, items)
+	}, batcher.WithSpool(spool))
+	b.Close()
+
+	want := [][]string{{"0", "1", "2"}, {"3", "4", "5"}, {"6", "7", "8"}, {"9"}}
+	if !reflect.DeepEqual(flushed, want) {
+		t.Errorf("Expected %v, got %v", want, flushed)
+	}
+}
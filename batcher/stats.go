@@ -0,0 +1,109 @@
+package batcher
+
+import (
+	"sync"
+	"time"
+)
+
+// FlushReason tells why a batch was flushed.
+type FlushReason int
+
+const (
+	// FlushReasonCount means the batch reached the maximum number of items.
+	FlushReasonCount FlushReason = iota
+	// FlushReasonWeight means the batch reached the maximum weight.
+	FlushReasonWeight
+	// FlushReasonTimeout means the timeout has elapsed.
+	FlushReasonTimeout
+	// FlushReasonManual means the batch was flushed by a Flush call.
+	FlushReasonManual
+	// FlushReasonClose means the batch was flushed on shutdown.
+	FlushReasonClose
+)
+
+func (r FlushReason) String() string {
+	switch r {
+	case FlushReasonCount:
+		return "count"
+	case FlushReasonWeight:
+		return "weight"
+	case FlushReasonTimeout:
+		return "timeout"
+	case FlushReasonManual:
+		return "manual"
+	case FlushReasonClose:
+		return "close"
+	}
+	return "unknown"
+}
+
+// FlushInfo describes a flushed batch.
+type FlushInfo struct {
+	Size      int           // Number of items in the batch.
+	Weight    int           // Total weight of the items, if they are weighed.
+	Reason    FlushReason   // Reason of the flush.
+	QueueWait time.Duration // Time since adding the oldest item until the start of the flush.
+	Latency   time.Duration // Duration of the flush including retries.
+	Attempts  int           // Number of flush attempts.
+	Err       error         // Error of the last attempt.
+}
+
+// Hooks receives the events of a Batcher.
+// The methods are called concurrently if there are flush workers or a queue.
+type Hooks interface {
+	// OnFlush is called after a batch is flushed.
+	OnFlush(FlushInfo)
+	// OnDrop is called when items are discarded due to the queue overflow.
+	OnDrop(count int)
+}
+
+// Stats is a snapshot of the Batcher statistics.
+type Stats struct {
+	Batches   int64                 // Number of flushed batches.
+	Items     int64                 // Number of flushed items.
+	Failed    int64                 // Number of batches failed after all attempts.
+	Dropped   int64                 // Number of items discarded due to the queue overflow.
+	Pending   int64                 // Number of added items, which are not flushed yet.
+	Reasons   map[FlushReason]int64 // Number of batches by flush reason.
+	Latency   time.Duration         // Total duration of flushes.
+	QueueWait time.Duration         // Total queue wait time of batches.
+}
+
+// stats accumulates the Batcher statistics.
+type stats struct {
+	mu    sync.Mutex
+	stats Stats // protected by mu
+}
+
+func (s *stats) flushed(info FlushInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Batches++
+	s.stats.Items += int64(info.Size)
+	if info.Err != nil {
+		s.stats.Failed++
+	}
+	if s.stats.Reasons == nil {
+		s.stats.Reasons = make(map[FlushReason]int64)
+	}
+	s.stats.Reasons[info.Reason]++
+	s.stats.Latency += info.Latency
+	s.stats.QueueWait += info.QueueWait
+}
+
+func (s *stats) dropped(count int) {
+	s.mu.Lock()
+	s.stats.Dropped += int64(count)
+	s.mu.Unlock()
+}
+
+func (s *stats) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := s.stats
+	snapshot.Reasons = make(map[FlushReason]int64, len(s.stats.Reasons))
+	for reason, n := range s.stats.Reasons {
+		snapshot.Reasons[reason] = n
+	}
+	return snapshot
+}
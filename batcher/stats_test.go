@@ -0,0 +1,119 @@
+package batcher_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Zamony/go/batcher"
+)
+
+type recordingHooks struct {
+	mu      sync.Mutex
+	flushes []batcher.FlushInfo
+	dropped int
+}
+
+func (h *recordingHooks) OnFlush(info batcher.FlushInfo) {
+	h.mu.Lock()
+	h.flushes = append(h.flushes, info)
+	h.mu.Unlock()
+}
+
+func (h *recordingHooks) OnDrop(count int) {
+	h.mu.Lock()
+	h.dropped += count
+	h.mu.Unlock()
+}
+
+func TestHooksAndStats(t *testing.T) {
+	hooks := &recordingHooks{}
+	flushFunc := func(context.Context, []int) {}
+	b := batcher.New(2, time.Hour, flushFunc, batcher.WithHooks[int](hooks))
+
+	if err := b.Add(context.Background(), 1, 2); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := b.Add(context.Background(), 3); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := b.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if err := b.Add(context.Background(), 4); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if stats := b.Stats(); stats.Pending != 1 {
+		t.Errorf("Expected 1 pending item, got %d", stats.Pending)
+	}
+	b.Close()
+
+	var reasons []batcher.FlushReason
+	var sizes []int
+	for _, info := range hooks.flushes {
+		reasons = append(reasons, info.Reason)
+		sizes = append(sizes, info.Size)
+		if info.Attempts != 1 || info.Err != nil {
+			t.Errorf("Expected single successful attempt, got %+v", info)
+		}
+	}
+	wantReasons := []batcher.FlushReason{
+		batcher.FlushReasonCount,
+		batcher.FlushReasonManual,
+		batcher.FlushReasonClose,
+	}
+	if !reflect.DeepEqual(reasons, wantReasons) {
+		t.Errorf("Expected reasons %v, got %v", wantReasons, reasons)
+	}
+	if !reflect.DeepEqual(sizes, []int{2, 1, 1}) {
+		t.Errorf("Expected sizes [2 1 1], got %v", sizes)
+	}
+
+	stats := b.Stats()
+	wantStats := batcher.Stats{
+		Batches: 3,
+		Items:   4,
+		Reasons: map[batcher.FlushReason]int64{
+			batcher.FlushReasonCount:  1,
+			batcher.FlushReasonManual: 1,
+			batcher.FlushReasonClose:  1,
+		},
+		Latency:   stats.Latency,
+		QueueWait: stats.QueueWait,
+	}
+	if !reflect.DeepEqual(stats, wantStats) {
+		t.Errorf("Expected %+v, got %+v", wantStats, stats)
+	}
+}
+
+func TestHooksDrop(t *testing.T) {
+	hooks := &recordingHooks{}
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	flushFunc := func(context.Context, []int) {
+		started <- struct{}{}
+		<-release
+	}
+
+	b := batcher.New(1, time.Hour, flushFunc,
+		batcher.WithHooks[int](hooks),
+		batcher.WithQueue[int](1, batcher.OverflowDropNewest),
+	)
+	if err := b.Add(context.Background(), 1); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	<-started
+	for i := range 3 {
+		if err := b.Add(context.Background(), i, i); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	close(release)
+	b.Close()
+
+	if hooks.dropped != 4 || b.Stats().Dropped != 4 {
+		t.Errorf("Expected 4 dropped items, got %d and %+v", hooks.dropped, b.Stats())
+	}
+}
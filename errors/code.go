@@ -0,0 +1,141 @@
+package errors
+
+// Code classifies an error, e.g. to map it to a response status.
+type Code int
+
+const (
+	CodeUnknown          Code = iota // Error without a code.
+	CodeInvalidArgument              // Client specified an invalid argument.
+	CodeNotFound                     // Requested entity was not found.
+	CodeConflict                     // Entity already exists or was concurrently modified.
+	CodePermissionDenied             // Caller has no permission to execute the operation.
+	CodeUnauthenticated              // Caller is not authenticated.
+	CodeUnavailable                  // Service is temporarily unavailable.
+	CodeInternal                     // Internal error.
+)
+
+func (c Code) String() string {
+	switch c {
+	case CodeInvalidArgument:
+		return "invalid argument"
+	case CodeNotFound:
+		return "not found"
+	case CodeConflict:
+		return "conflict"
+	case CodePermissionDenied:
+		return "permission denied"
+	case CodeUnauthenticated:
+		return "unauthenticated"
+	case CodeUnavailable:
+		return "unavailable"
+	case CodeInternal:
+		return "internal"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the code as its name, so the encoding
+// doesn't depend on the order of the constants.
+func (c Code) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText decodes the code from its name.
+// Unknown names are decoded as CodeUnknown.
+func (c *Code) UnmarshalText(text []byte) error {
+	*c = CodeUnknown
+	for code := CodeInvalidArgument; code <= CodeInternal; code++ {
+		if code.String() == string(text) {
+			*c = code
+			break
+		}
+	}
+	return nil
+}
+
+// HTTPStatus returns the default HTTP status code for the error code.
+func (c Code) HTTPStatus() int {
+	switch c {
+	case CodeInvalidArgument:
+		return 400 // Bad Request
+	case CodeNotFound:
+		return 404 // Not Found
+	case CodeConflict:
+		return 409 // Conflict
+	case CodePermissionDenied:
+		return 403 // Forbidden
+	case CodeUnauthenticated:
+		return 401 // Unauthorized
+	case CodeUnavailable:
+		return 503 // Service Unavailable
+	}
+	return 500 // Internal Server Error
+}
+
+type codeError struct {
+	err  error
+	code Code
+}
+
+func (c *codeError) Unwrap() error {
+	return c.err
+}
+
+func (c *codeError) Error() string {
+	return c.err.Error()
+}
+
+func (c *codeError) Code() Code {
+	return c.code
+}
+
+type coder interface {
+	error
+	Code() Code
+}
+
+// WithCode attaches the code to the error.
+// Also adds a stacktrace to the error if it doesn't have one.
+func WithCode(err error, code Code) error {
+	if err == nil {
+		return nil
+	}
+
+	newErr := &codeError{err, code}
+	if b, ok := err.(stacktracer); ok {
+		return &baseError{newErr, sharedStack(b)}
+	}
+	if r, ok := err.(remoteStacker); ok {
+		return wrapRemote(newErr, r.Stack())
+	}
+	return &baseError{newErr, frames()}
+}
+
+// CodeOf returns the code of the first error in err's tree, which has a code.
+// Errors may provide their codes by implementing Code() Code method.
+// Returns CodeUnknown if there is no such error.
+func CodeOf(err error) Code {
+	if c, ok := AsType[coder](err); ok {
+		return c.Code()
+	}
+	return CodeUnknown
+}
+
+// HasCode reports whether any error in err's tree has the code.
+func HasCode(err error, code Code) bool {
+	for err := range Walk(err) {
+		if c, ok := err.(coder); ok && c.Code() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPStatus returns the HTTP status code for the error using the code of the error.
+// Returns 200 for nil error.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return 200 // OK
+	}
+	return CodeOf(err).HTTPStatus()
+}
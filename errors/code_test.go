@@ -0,0 +1,94 @@
+package errors_test
+
+import (
+	"testing"
+
+	"github.com/Zamony/go/errors"
+)
+
+type codedError struct{}
+
+func (codedError) Error() string     { return "coded" }
+func (codedError) Code() errors.Code { return errors.CodeUnavailable }
+
+func TestCode(t *testing.T) {
+	errSentinel := errors.SentinelError("sentinel")
+	errNotFound := errors.WithCode(errSentinel, errors.CodeNotFound)
+	errConflict := errors.WithCode(errors.New("conflict"), errors.CodeConflict)
+
+	testCases := []struct {
+		TestName string
+		Error    error
+		Code     errors.Code
+		Status   int
+	}{
+		{
+			TestName: "No error",
+			Error:    nil,
+			Code:     errors.CodeUnknown,
+			Status:   200,
+		},
+		{
+			TestName: "Error without code",
+			Error:    errSentinel,
+			Code:     errors.CodeUnknown,
+			Status:   500,
+		},
+		{
+			TestName: "Error with code",
+			Error:    errNotFound,
+			Code:     errors.CodeNotFound,
+			Status:   404,
+		},
+		{
+			TestName: "Wrapped error with code",
+			Error:    errors.Wrap(errNotFound),
+			Code:     errors.CodeNotFound,
+			Status:   404,
+		},
+		{
+			TestName: "Wrapfed error with code",
+			Error:    errors.Wrapf(errNotFound, "get user"),
+			Code:     errors.CodeNotFound,
+			Status:   404,
+		},
+		{
+			TestName: "Joined errors with codes",
+			Error:    errors.Join(errSentinel, errConflict, errNotFound),
+			Code:     errors.CodeConflict,
+			Status:   409,
+		},
+		{
+			TestName: "Overridden code",
+			Error:    errors.WithCode(errors.Wrapf(errNotFound, "get user"), errors.CodeInternal),
+			Code:     errors.CodeInternal,
+			Status:   500,
+		},
+		{
+			TestName: "Custom error with code",
+			Error:    errors.Wrapf(codedError{}, "custom"),
+			Code:     errors.CodeUnavailable,
+			Status:   503,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.TestName, func(t *testing.T) {
+			equal(t, errors.CodeOf(tc.Error), tc.Code)
+			equal(t, errors.HTTPStatus(tc.Error), tc.Status)
+		})
+	}
+}
+
+func TestHasCode(t *testing.T) {
+	err := errors.Join(
+		errors.WithCode(errors.New("conflict"), errors.CodeConflict),
+		errors.Wrapf(errors.WithCode(errors.New("not found"), errors.CodeNotFound), "wrapf"),
+	)
+	equal(t, errors.HasCode(err, errors.CodeConflict), true)
+	equal(t, errors.HasCode(err, errors.CodeNotFound), true)
+	equal(t, errors.HasCode(err, errors.CodeInternal), false)
+	equal(t, errors.HasCode(nil, errors.CodeUnknown), false)
+	equal(t, errors.WithCode(nil, errors.CodeInternal), nil)
+	equal(t, errors.WithCode(errors.SentinelError("sentinel"), errors.CodeInternal).Error(), "sentinel")
+}
@@ -0,0 +1,140 @@
+package errors
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ItemError is an error of an item added to Collector by AddIndex or AddLabel.
+type ItemError struct {
+	Index int    // Index of the item, if Label is empty.
+	Label string // Label of the item.
+	Err   error
+}
+
+func (i *ItemError) Error() string {
+	if i.Label != "" {
+		return fmt.Sprintf("%s: %s", i.Label, i.Err)
+	}
+	return fmt.Sprintf("item %d: %s", i.Index, i.Err)
+}
+
+func (i *ItemError) Unwrap() error {
+	return i.Err
+}
+
+// Collector accumulates errors, e.g. of the iterations of a loop.
+// It is safe for concurrent use. Zero value is ready to use and stores all errors.
+type Collector struct {
+	mu      sync.Mutex
+	limit   int
+	errs    []error
+	dropped int
+	frames  StackFrames
+	remote  Stack // the stacktrace of the first failure, if it was decoded
+}
+
+// NewCollector creates a collector storing at most limit errors.
+// Other errors are dropped and only counted.
+// Non-positive limit means no limit.
+func NewCollector(limit int) *Collector {
+	return &Collector{limit: limit}
+}
+
+// Add adds the error to the collector.
+// Nil errors are ignored.
+func (c *Collector) Add(err error) {
+	if err == nil {
+		return
+	}
+	c.add(err)
+}
+
+// AddIndex adds the error of the item with the index.
+// Nil errors are ignored.
+func (c *Collector) AddIndex(index int, err error) {
+	if err == nil {
+		return
+	}
+	c.add(&ItemError{Index: index, Err: err})
+}
+
+// AddLabel adds the error of the item with the label.
+// Nil errors are ignored.
+func (c *Collector) AddLabel(label string, err error) {
+	if err == nil {
+		return
+	}
+	c.add(&ItemError{Label: label, Err: err})
+}
+
+func (c *Collector) add(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.errs) == 0 && c.dropped == 0 {
+		// The stacktrace of the first failure.
+		switch b := unwrapItem(err).(type) {
+		case stacktracer:
+			c.frames = sharedStack(b)
+		case remoteStacker:
+			c.remote = b.Stack()
+		default:
+			c.frames = capture(3) // +1 add, +1 Add, +1 the caller
+		}
+	}
+	if c.limit > 0 && len(c.errs) >= c.limit {
+		c.dropped++
+		return
+	}
+	c.errs = append(c.errs, err)
+}
+
+func unwrapItem(err error) error {
+	if i, ok := err.(*ItemError); ok {
+		return i.Err
+	}
+	return err
+}
+
+// Len returns the number of added errors including the dropped ones.
+func (c *Collector) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.errs) + c.dropped
+}
+
+// Dropped returns the number of errors dropped because of the limit.
+func (c *Collector) Dropped() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dropped
+}
+
+// Err returns the collected errors joined into one like Join does
+// with the stacktrace of the first failure.
+// Number of the dropped errors is reported by the last joined error.
+// Returns nil if no errors were added.
+func (c *Collector) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.errs) == 0 {
+		return nil
+	}
+	errs := slices.Clone(c.errs)
+	if c.dropped > 0 {
+		errs = append(errs, fmt.Errorf("%d more errors dropped", c.dropped))
+	}
+	if len(c.remote) > 0 {
+		if len(errs) == 1 {
+			return wrapRemote(errs[0], c.remote)
+		}
+		return wrapRemote(&joinError{errs, nil}, c.remote)
+	}
+	if len(errs) == 1 {
+		return &baseError{errs[0], c.frames}
+	}
+	return &joinError{errs, c.frames}
+}
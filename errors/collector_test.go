@@ -0,0 +1,111 @@
+package errors_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/Zamony/go/errors"
+)
+
+func TestCollector(t *testing.T) {
+	errFirst := errors.New("first")
+	errSentinel := errors.SentinelError("sentinel")
+
+	testCases := []struct {
+		TestName string
+		Limit    int
+		Add      func(c *errors.Collector)
+		Message  string
+		Len      int
+		Dropped  int
+	}{
+		{
+			TestName: "No errors",
+			Add: func(c *errors.Collector) {
+				c.Add(nil)
+				c.AddIndex(0, nil)
+				c.AddLabel("nil", nil)
+			},
+		},
+		{
+			TestName: "Single error",
+			Add: func(c *errors.Collector) {
+				c.Add(errFirst)
+			},
+			Message: "first",
+			Len:     1,
+		},
+		{
+			TestName: "Indexed and labeled errors",
+			Add: func(c *errors.Collector) {
+				c.AddIndex(3, errFirst)
+				c.AddLabel("user 42", errSentinel)
+			},
+			Message: `["item 3: first" "user 42: sentinel"]`,
+			Len:     2,
+		},
+		{
+			TestName: "Dropped errors",
+			Limit:    1,
+			Add: func(c *errors.Collector) {
+				c.Add(errFirst)
+				c.Add(errSentinel)
+				c.Add(errSentinel)
+			},
+			Message: `["first" "2 more errors dropped"]`,
+			Len:     3,
+			Dropped: 2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.TestName, func(t *testing.T) {
+			c := errors.NewCollector(tc.Limit)
+			tc.Add(c)
+
+			err := c.Err()
+			if tc.Message == "" {
+				equal(t, err, nil)
+			} else {
+				equal(t, err.Error(), tc.Message)
+				equal(t, errors.Is(err, errFirst), true)
+			}
+			equal(t, c.Len(), tc.Len)
+			equal(t, c.Dropped(), tc.Dropped)
+		})
+	}
+}
+
+func TestCollectorStack(t *testing.T) {
+	errFirst := errors.New("first")
+
+	var c errors.Collector
+	c.Add(errors.SentinelError("sentinel"))
+	c.AddIndex(1, errFirst)
+	stack := errors.StackOf(c.Err())
+	equal(t, stack[0].Function, "github.com/Zamony/go/errors_test.TestCollectorStack")
+	equal(t, stack[0].Line, 82)
+
+	var s errors.Collector
+	s.AddLabel("first", errFirst)
+	s.Add(errors.SentinelError("sentinel"))
+	equal(t, errors.StackTrace(s.Err()), errors.StackTrace(errFirst))
+
+	item, ok := errors.AsType[*errors.ItemError](s.Err())
+	equal(t, ok, true)
+	equal(t, item.Label, "first")
+}
+
+func TestCollectorConcurrent(t *testing.T) {
+	c := errors.NewCollector(10)
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Go(func() {
+			c.AddIndex(i, errors.SentinelError("failed"))
+		})
+	}
+	wg.Wait()
+
+	equal(t, c.Len(), 100)
+	equal(t, c.Dropped(), 90)
+	equal(t, len(c.Err().(interface{ Unwrap() []error }).Unwrap()), 11)
+}
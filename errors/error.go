@@ -4,7 +4,7 @@ import (
 	stderrors "errors"
 	"fmt"
 	"io"
-	"runtime"
+	"sync/atomic"
 )
 
 type baseError struct {
@@ -57,13 +57,26 @@ func Newf(format string, a ...any) error {
 	return &baseError{stderrors.New(fmt.Sprintf(format, a...)), frames()}
 }
 
-const maxStackDepth = 32
+// DefaultStackDepth is the default maximum number of captured stack frames.
+const DefaultStackDepth = 32
 
-func frames() []uintptr {
+var stackDepth atomic.Int64
+
+func init() {
+	stackDepth.Store(DefaultStackDepth)
+}
+
+// SetStackDepth sets the maximum number of stack frames captured by
+// the functions of this package. Zero disables capturing stacktraces,
+// so errors are created faster, but StackTrace returns an empty string.
+// It is safe to call concurrently with other functions.
+func SetStackDepth(depth int) {
+	stackDepth.Store(int64(max(depth, 0)))
+}
+
+func frames() StackFrames {
 	const skip = 3 // +1 Callers, +1 frames, +1 New
-	var pcs [maxStackDepth]uintptr
-	depth := runtime.Callers(skip, pcs[:])
-	return pcs[:depth]
+	return capture(skip)
 }
 
 type stacktracer interface {
@@ -75,7 +88,8 @@ func Wrap(err error) error {
 	if err == nil {
 		return nil
 	}
-	if _, ok := err.(stacktracer); ok {
+	switch err.(type) {
+	case stacktracer, remoteStacker:
 		return err
 	}
 	return &baseError{err, frames()}
@@ -88,14 +102,31 @@ func Wrapf(err error, format string, a ...any) error {
 		return nil
 	}
 
-	msg := fmt.Sprintf(format, a...)
-	newErr := fmt.Errorf("%s: %w", msg, err)
+	newErr := &wrapError{fmt.Sprintf(format, a...), err}
 	if b, ok := err.(stacktracer); ok {
-		return &baseError{newErr, b.StackTrace()}
+		return &baseError{newErr, sharedStack(b)}
+	}
+	if r, ok := err.(remoteStacker); ok {
+		return wrapRemote(newErr, r.Stack())
 	}
 	return &baseError{newErr, frames()}
 }
 
+// wrapError prefixes the message of the wrapped error,
+// like fmt.Errorf("%s: %w") does.
+type wrapError struct {
+	msg string
+	err error
+}
+
+func (e *wrapError) Error() string {
+	return e.msg + ": " + e.err.Error()
+}
+
+func (e *wrapError) Unwrap() error {
+	return e.err
+}
+
 type joinError struct {
 	errors []error
 	frames StackFrames
@@ -124,6 +155,8 @@ func (e *joinError) Error() string {
 	})
 }
 
+// Format prints the joined errors on a single line, %+v adds their stacktraces inline.
+// The format is kept for log lines, use FormatTree for a multi-line tree.
 func (e *joinError) Format(state fmt.State, verb rune) {
 	switch verb {
 	case 'v':
@@ -145,14 +178,18 @@ func Join(errs ...error) error {
 	// Could be Join(errA, errB error, errsTail ...error) error.
 	// But it would make it impossible to unpack errors: Join(errors...).
 	var stack StackFrames
+	var remoteStack Stack
 	jerrs := make([]error, 0, len(errs))
 	for _, err := range errs {
 		if err == nil {
 			continue
 		}
-		if len(stack) == 0 {
-			if b, ok := err.(stacktracer); ok {
-				stack = b.StackTrace()
+		if len(stack) == 0 && len(remoteStack) == 0 {
+			switch b := err.(type) {
+			case stacktracer:
+				stack = sharedStack(b)
+			case remoteStacker:
+				remoteStack = b.Stack()
 			}
 		}
 		jerrs = append(jerrs, err)
@@ -161,6 +198,12 @@ func Join(errs ...error) error {
 	if len(jerrs) == 0 {
 		return nil
 	}
+	if len(remoteStack) > 0 {
+		if len(jerrs) == 1 {
+			return wrapRemote(jerrs[0], remoteStack)
+		}
+		return wrapRemote(&joinError{jerrs, nil}, remoteStack)
+	}
 	if len(stack) == 0 {
 		stack = frames()
 	}
@@ -0,0 +1,53 @@
+package errors
+
+import "log/slog"
+
+type fieldsError struct {
+	err    error
+	fields []slog.Attr
+}
+
+func (f *fieldsError) Unwrap() error {
+	return f.err
+}
+
+func (f *fieldsError) Error() string {
+	return f.err.Error()
+}
+
+// WithFields attaches structured fields to the error.
+// Also adds a stacktrace to the error if it doesn't have one.
+func WithFields(err error, attrs ...slog.Attr) error {
+	if err == nil {
+		return nil
+	}
+
+	newErr := &fieldsError{err, attrs}
+	if b, ok := err.(stacktracer); ok {
+		return &baseError{newErr, sharedStack(b)}
+	}
+	if r, ok := err.(remoteStacker); ok {
+		return wrapRemote(newErr, r.Stack())
+	}
+	return &baseError{newErr, frames()}
+}
+
+// Fields returns the fields attached to any error in err's tree.
+// If a key is attached multiple times, the outermost value wins.
+func Fields(err error) []slog.Attr {
+	var attrs []slog.Attr
+	seen := make(map[string]bool)
+	for err := range Walk(err) {
+		f, ok := err.(*fieldsError)
+		if !ok {
+			continue
+		}
+		for _, attr := range f.fields {
+			if !seen[attr.Key] {
+				seen[attr.Key] = true
+				attrs = append(attrs, attr)
+			}
+		}
+	}
+	return attrs
+}
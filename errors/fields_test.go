@@ -0,0 +1,36 @@
+package errors_test
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/Zamony/go/errors"
+)
+
+func TestFields(t *testing.T) {
+	errSentinel := errors.SentinelError("sentinel")
+	err := errors.WithFields(errSentinel, slog.String("user_id", "u1"), slog.Int("attempt", 1))
+	err = errors.Wrapf(err, "get order")
+	err = errors.WithFields(err, slog.Int64("order_id", 42), slog.Int("attempt", 2))
+	err = errors.Join(err, errors.WithFields(errors.New("close"), slog.Bool("closed", false)))
+
+	want := []slog.Attr{
+		slog.Int64("order_id", 42),
+		slog.Int("attempt", 2),
+		slog.String("user_id", "u1"),
+		slog.Bool("closed", false),
+	}
+	equal(t, errors.Fields(err), want)
+	equal(t, errors.Is(err, errSentinel), true)
+	equal(t, errors.Fields(errSentinel), []slog.Attr(nil))
+	equal(t, errors.WithFields(nil, slog.Int("a", 1)), nil)
+}
+
+func TestFieldsMessageAndStack(t *testing.T) {
+	err := errors.WithFields(errors.SentinelError("sentinel"), slog.Int("a", 1))
+	equal(t, err.Error(), "sentinel")
+	equal(t, errors.StackTrace(err), "errors_test.TestFieldsMessageAndStack:30/testing.tRunner/runtime.goexit")
+
+	err = errors.WithFields(newError("new"), slog.Int("a", 1))
+	equal(t, errors.StackTrace(err), "errors_test.newError:12/TestFieldsMessageAndStack/testing.tRunner/runtime.goexit")
+}
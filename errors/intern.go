@@ -0,0 +1,131 @@
+package errors
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// maxInternedStacks bounds memory used by the cache of stacktraces.
+// When the cache is full, the stacktraces not used recently are evicted.
+const maxInternedStacks = 4096
+
+// internedStack is a stacktrace shared by all errors created at the same place.
+type internedStack struct {
+	pcs  StackFrames
+	used atomic.Bool // the stacktrace was used since the clock hand passed it
+
+	once  sync.Once
+	stack Stack  // symbolized pcs
+	trace string // compact form of stack
+}
+
+func (s *internedStack) symbolize() {
+	s.once.Do(func() {
+		s.stack = symbolize(s.pcs)
+		s.trace = compactStackTrace(s.stack)
+	})
+}
+
+// stacks is the cache of stacktraces with CLOCK eviction policy:
+// the hand goes around the ring evicting the first stacktrace,
+// which wasn't used since the hand passed it last time.
+var stacks = struct {
+	sync.RWMutex
+	byPCs map[string]*internedStack
+	ring  []*internedStack
+	hand  int
+}{
+	byPCs: make(map[string]*internedStack),
+}
+
+// lookup returns the shared stacktrace with the program counters.
+// Returns nil if the stacktrace is not cached.
+func lookup(pcs StackFrames) *internedStack {
+	if len(pcs) == 0 {
+		return nil
+	}
+
+	stacks.RLock()
+	s := stacks.byPCs[pcsKey(pcs)]
+	stacks.RUnlock()
+	if s != nil {
+		s.used.Store(true)
+	}
+	return s
+}
+
+// intern returns the shared stacktrace with the program counters
+// adding it to the cache if necessary.
+// Returns nil if the stacktrace is empty.
+func intern(pcs StackFrames) *internedStack {
+	if len(pcs) == 0 {
+		return nil
+	}
+	if s := lookup(pcs); s != nil {
+		return s
+	}
+
+	stacks.Lock()
+	defer stacks.Unlock()
+	key := pcsKey(pcs)
+	if s := stacks.byPCs[key]; s != nil {
+		return s
+	}
+
+	s := &internedStack{pcs: copystack(pcs)}
+	if len(stacks.ring) < maxInternedStacks {
+		stacks.ring = append(stacks.ring, s)
+	} else {
+		for stacks.ring[stacks.hand].used.Swap(false) {
+			stacks.hand = (stacks.hand + 1) % len(stacks.ring)
+		}
+		// Errors keep the evicted program counters, they are just not shared anymore.
+		delete(stacks.byPCs, pcsKey(stacks.ring[stacks.hand].pcs))
+		stacks.ring[stacks.hand] = s
+		stacks.hand = (stacks.hand + 1) % len(stacks.ring)
+	}
+	stacks.byPCs[pcsKey(s.pcs)] = s
+	return s
+}
+
+// pcsKey reinterprets the program counters as a string without copying,
+// so the string must not outlive pcs or be used after pcs are modified.
+func pcsKey(pcs StackFrames) string {
+	size := len(pcs) * int(unsafe.Sizeof(pcs[0]))
+	return unsafe.String((*byte)(unsafe.Pointer(unsafe.SliceData(pcs))), size)
+}
+
+// capture captures the stack like runtime.Callers(skip) called instead of capture
+// and returns the shared program counters.
+func capture(skip int) StackFrames {
+	depth := int(stackDepth.Load())
+	if depth == 0 {
+		return nil
+	}
+
+	var buf [2 * DefaultStackDepth]uintptr
+	pcs := buf[:]
+	if depth > len(buf) {
+		pcs = make([]uintptr, depth)
+	}
+	pcs = pcs[:runtime.Callers(skip+1, pcs[:depth])]
+
+	if s := intern(pcs); s != nil {
+		return s.pcs
+	}
+	return copystack(pcs)
+}
+
+// sharedStack returns the stacktrace of the error, avoiding a copy
+// if the error is created by this package.
+func sharedStack(err stacktracer) StackFrames {
+	switch x := err.(type) {
+	case *baseError:
+		return x.frames
+	case *joinError:
+		return x.frames
+	}
+	return err.StackTrace()
+}
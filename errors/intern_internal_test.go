@@ -0,0 +1,36 @@
+package errors
+
+import "testing"
+
+func TestInternEviction(t *testing.T) {
+	fake := func(i int) StackFrames {
+		return StackFrames{uintptr(1 << 40), uintptr(i)} // never symbolized
+	}
+
+	hot := intern(fake(0))
+	for i := 1; i <= 2*maxInternedStacks; i++ {
+		intern(fake(i))
+		lookup(fake(0)) // keeps the stacktrace in the cache
+	}
+
+	if lookup(fake(0)) != hot {
+		t.Error("Expected the used stacktrace to stay in the cache")
+	}
+	if lookup(fake(1)) != nil {
+		t.Error("Expected the unused stacktrace to be evicted")
+	}
+	if lookup(fake(2*maxInternedStacks)) == nil {
+		t.Error("Expected the recent stacktrace to be cached")
+	}
+	if got := len(stacks.byPCs); got > maxInternedStacks {
+		t.Errorf("Expected at most %d cached stacktraces, got %d", maxInternedStacks, got)
+	}
+}
+
+func TestSymbolizeDoesntIntern(t *testing.T) {
+	pcs := StackFrames{uintptr(1 << 41)}
+	_ = pcs.Symbolize()
+	if lookup(pcs) != nil {
+		t.Error("Expected user stacktrace not to be cached")
+	}
+}
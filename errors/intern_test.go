@@ -0,0 +1,42 @@
+package errors_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/Zamony/go/errors"
+)
+
+func TestStackInterning(t *testing.T) {
+	errs := make([]error, 2)
+	for i := range errs {
+		errs[i] = errors.New("new")
+	}
+	equal(t, errors.StackTrace(errs[0]), errors.StackTrace(errs[1]))
+
+	stack := errors.StackOf(errs[0])
+	stack[0].Function = "modified"
+	equal(t, errors.StackOf(errs[1])[0].Function, "github.com/Zamony/go/errors_test.TestStackInterning")
+
+	allocs := testing.AllocsPerRun(10, func() {
+		_ = errors.StackTrace(errs[0])
+	})
+	equal(t, allocs, 0.0)
+}
+
+func TestStackInterningConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	traces := make([]string, 8)
+	for i := range traces {
+		wg.Go(func() {
+			for range 100 {
+				traces[i] = errors.StackTrace(errors.Wrapf(errors.New("new"), "wrap"))
+			}
+		})
+	}
+	wg.Wait()
+
+	for _, trace := range traces {
+		equal(t, trace, traces[0])
+	}
+}
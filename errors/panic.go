@@ -0,0 +1,68 @@
+package errors
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// PanicError is an error created from a recovered panic.
+type PanicError struct {
+	Value any // Value passed to panic.
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (p *PanicError) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// Try calls the function and converts its panic into an error.
+// The stacktrace of the error points at the panic site.
+func Try(fun func() error) (err error) {
+	defer Recover(&err)
+	return fun()
+}
+
+// Recover converts a panic into an error and stores it into errp.
+// It must be deferred directly: defer errors.Recover(&err).
+// The stacktrace of the error points at the panic site.
+func Recover(errp *error) {
+	if v := recover(); v != nil {
+		*errp = &baseError{&PanicError{v}, panicFrames()}
+	}
+}
+
+func panicFrames() []uintptr {
+	const reserve = 16 // for deferred call and runtime frames
+	depth := int(stackDepth.Load())
+	if depth == 0 {
+		return nil
+	}
+
+	pcs := make([]uintptr, depth+reserve)
+	pcs = pcs[:runtime.Callers(1, pcs)]
+	for i := range pcs {
+		if functionName(pcs[i]) == "runtime.gopanic" {
+			pcs = pcs[i+1:]
+			break
+		}
+	}
+	for len(pcs) > 0 && strings.HasPrefix(functionName(pcs[0]), "runtime.") {
+		pcs = pcs[1:] // e.g. runtime.sigpanic of a nil dereference
+	}
+	pcs = pcs[:min(len(pcs), depth)]
+	if s := intern(pcs); s != nil {
+		return s.pcs
+	}
+	return pcs
+}
+
+func functionName(pc uintptr) string {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return frame.Function
+}
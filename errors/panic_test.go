@@ -0,0 +1,54 @@
+package errors_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/Zamony/go/errors"
+)
+
+func panicWithValue() error {
+	panic("boom")
+}
+
+func panicWithNil() error {
+	var m map[string]int
+	m["a"] = 1
+	return nil
+}
+
+func TestTry(t *testing.T) {
+	err := errors.Try(panicWithValue)
+	equal(t, err.Error(), "panic: boom")
+	equal(t, errors.StackTrace(err)[:47], "errors_test.panicWithValue:11/errors.Try/errors")
+	panicErr, ok := errors.AsType[*errors.PanicError](err)
+	equal(t, ok, true)
+	equal(t, panicErr.Value, "boom")
+
+	err = errors.Try(panicWithNil)
+	equal(t, err.Error(), "panic: assignment to entry in nil map")
+	equal(t, errors.StackTrace(err)[:45], "errors_test.panicWithNil:16/errors.Try/errors")
+	_, ok = errors.AsType[runtime.Error](err)
+	equal(t, ok, true)
+
+	errSentinel := errors.SentinelError("sentinel")
+	err = errors.Try(func() error { panic(errSentinel) })
+	equal(t, errors.Is(err, errSentinel), true)
+
+	err = errors.Try(func() error { return errSentinel })
+	equal(t, err, errSentinel)
+}
+
+func TestRecover(t *testing.T) {
+	run := func() (err error) {
+		defer errors.Recover(&err)
+		var p *struct{ X int }
+		_ = p.X
+		return nil
+	}
+
+	err := run()
+	equal(t, errors.StackTrace(err), "errors_test.TestRecover.func1:46/TestRecover/testing.tRunner/runtime.goexit")
+	_, ok := errors.AsType[runtime.Error](err)
+	equal(t, ok, true)
+}
@@ -0,0 +1,106 @@
+package errors
+
+import "time"
+
+type retryError struct {
+	err   error
+	after time.Duration
+}
+
+func (r *retryError) Unwrap() error {
+	return r.err
+}
+
+func (r *retryError) Error() string {
+	return r.err.Error()
+}
+
+func (r *retryError) Retryable() bool {
+	return true
+}
+
+func (r *retryError) RetryAfter() time.Duration {
+	return r.after
+}
+
+// Retryable marks the error as worth retrying.
+// Also adds a stacktrace to the error if it doesn't have one.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	newErr := &retryError{err, 0}
+	if b, ok := err.(stacktracer); ok {
+		return &baseError{newErr, sharedStack(b)}
+	}
+	if r, ok := err.(remoteStacker); ok {
+		return wrapRemote(newErr, r.Stack())
+	}
+	return &baseError{newErr, frames()}
+}
+
+// RetryableAfter marks the error as worth retrying after the given delay.
+// Also adds a stacktrace to the error if it doesn't have one.
+func RetryableAfter(err error, after time.Duration) error {
+	if err == nil {
+		return nil
+	}
+
+	newErr := &retryError{err, after}
+	if b, ok := err.(stacktracer); ok {
+		return &baseError{newErr, sharedStack(b)}
+	}
+	if r, ok := err.(remoteStacker); ok {
+		return wrapRemote(newErr, r.Stack())
+	}
+	return &baseError{newErr, frames()}
+}
+
+// IsRetryable reports whether any error in err's tree is worth retrying.
+// Besides errors marked with Retryable, these are errors implementing
+// Retryable() bool, Timeout() bool or Temporary() bool methods returning true,
+// e.g. timeouts of net.Error or context.DeadlineExceeded.
+// Retryable() bool takes precedence over the other methods
+// and over the errors wrapped by the error, so an error can veto retries
+// of e.g. a timeout it wraps.
+func IsRetryable(err error) bool {
+	switch x := err.(type) {
+	case nil:
+		return false
+	case interface{ Retryable() bool }:
+		return x.Retryable()
+	}
+	if x, ok := err.(interface{ Timeout() bool }); ok && x.Timeout() {
+		return true
+	}
+	if x, ok := err.(interface{ Temporary() bool }); ok && x.Temporary() {
+		return true
+	}
+
+	switch x := err.(type) {
+	case interface{ Unwrap() error }:
+		return IsRetryable(x.Unwrap())
+	case interface{ Unwrap() []error }:
+		for _, err := range x.Unwrap() {
+			if IsRetryable(err) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RetryAfter returns the longest retry delay suggested by the errors in err's tree.
+// Returns false if there is no suggestion.
+func RetryAfter(err error) (time.Duration, bool) {
+	var after time.Duration
+	found := false
+	for err := range Walk(err) {
+		if x, ok := err.(interface{ RetryAfter() time.Duration }); ok && x.RetryAfter() > 0 {
+			after = max(after, x.RetryAfter())
+			found = true
+		}
+	}
+	return after, found
+}
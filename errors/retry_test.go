@@ -0,0 +1,117 @@
+package errors_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Zamony/go/errors"
+)
+
+func TestIsRetryable(t *testing.T) {
+	errSentinel := errors.SentinelError("sentinel")
+	testCases := []struct {
+		TestName string
+		Error    error
+		Result   bool
+	}{
+		{
+			TestName: "No error",
+			Error:    nil,
+			Result:   false,
+		},
+		{
+			TestName: "Unmarked error",
+			Error:    errors.New("new"),
+			Result:   false,
+		},
+		{
+			TestName: "Retryable error",
+			Error:    errors.Retryable(errSentinel),
+			Result:   true,
+		},
+		{
+			TestName: "Wrapfed retryable error",
+			Error:    errors.Wrapf(errors.Retryable(errSentinel), "wrapf"),
+			Result:   true,
+		},
+		{
+			TestName: "Joined retryable error",
+			Error:    errors.Join(errSentinel, errors.RetryableAfter(errors.New("new"), time.Second)),
+			Result:   true,
+		},
+		{
+			TestName: "Context deadline",
+			Error:    errors.Wrapf(context.DeadlineExceeded, "wrapf"),
+			Result:   true,
+		},
+		{
+			TestName: "Context cancellation",
+			Error:    errors.Wrapf(context.Canceled, "wrapf"),
+			Result:   false,
+		},
+		{
+			TestName: "Network timeout",
+			Error:    errors.Wrap(&net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}),
+			Result:   true,
+		},
+		{
+			TestName: "Network error",
+			Error:    errors.Wrap(&net.OpError{Op: "dial", Err: fmt.Errorf("refused")}),
+			Result:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.TestName, func(t *testing.T) {
+			equal(t, errors.IsRetryable(tc.Error), tc.Result)
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	err := errors.Join(
+		errors.RetryableAfter(errors.New("foo"), time.Second),
+		errors.Wrapf(errors.RetryableAfter(errors.SentinelError("bar"), time.Minute), "wrapf"),
+		errors.Retryable(errors.New("baz")),
+	)
+	after, ok := errors.RetryAfter(err)
+	equal(t, after, time.Minute)
+	equal(t, ok, true)
+
+	_, ok = errors.RetryAfter(errors.Retryable(errors.New("new")))
+	equal(t, ok, false)
+	equal(t, errors.Retryable(nil), nil)
+	equal(t, errors.Retryable(errors.SentinelError("sentinel")).Error(), "sentinel")
+}
+
+func TestRetryableStack(t *testing.T) {
+	errSentinel := errors.SentinelError("sentinel")
+
+	err := errors.Retryable(errSentinel)
+	equal(t, errors.StackTrace(err), "errors_test.TestRetryableStack:94/testing.tRunner/runtime.goexit")
+	err = errors.RetryableAfter(errSentinel, time.Second)
+	equal(t, errors.StackTrace(err), "errors_test.TestRetryableStack:96/testing.tRunner/runtime.goexit")
+	err = errors.Retryable(newError("new"))
+	equal(t, errors.StackTrace(err), "errors_test.newError:12/TestRetryableStack/testing.tRunner/runtime.goexit")
+}
+
+// permanentError vetoes retries of the error it wraps.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string   { return "permanent: " + e.err.Error() }
+func (e *permanentError) Unwrap() error   { return e.err }
+func (e *permanentError) Retryable() bool { return false }
+
+func TestIsRetryableVeto(t *testing.T) {
+	err := errors.Wrap(&permanentError{context.DeadlineExceeded})
+	equal(t, errors.IsRetryable(err), false)
+
+	err = errors.Join(err, errors.Retryable(errors.New("new")))
+	equal(t, errors.IsRetryable(err), true)
+}
@@ -0,0 +1,80 @@
+package errors
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+)
+
+// Name and attribute keys of an exception event following OpenTelemetry semantic conventions.
+const (
+	ExceptionEventName     = "exception"
+	ExceptionTypeKey       = "exception.type"
+	ExceptionMessageKey    = "exception.message"
+	ExceptionStacktraceKey = "exception.stacktrace"
+)
+
+// Span is a tracing span errors are recorded on.
+// Implement it on top of a tracing SDK, e.g. for OpenTelemetry:
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) AddEvent(name string, attrs ...slog.Attr) {
+//		s.Span.AddEvent(name, trace.WithAttributes(convert(attrs)...))
+//	}
+type Span interface {
+	AddEvent(name string, attrs ...slog.Attr)
+}
+
+// RecordError adds the exception event with attributes of the error to the span.
+// Nil error is ignored.
+func RecordError(span Span, err error) {
+	if err == nil {
+		return
+	}
+	span.AddEvent(ExceptionEventName, ExceptionAttrs(err)...)
+}
+
+// ExceptionAttrs returns attributes describing the error as an exception:
+// type of the wrapped error, message, panic-style stacktrace
+// and the fields attached to the error.
+func ExceptionAttrs(err error) []slog.Attr {
+	if err == nil {
+		return nil
+	}
+
+	attrs := []slog.Attr{
+		slog.String(ExceptionTypeKey, exceptionType(err)),
+		slog.String(ExceptionMessageKey, err.Error()),
+	}
+	if stack := StackOf(err); len(stack) > 0 {
+		attrs = append(attrs, slog.String(ExceptionStacktraceKey, stack.String()))
+	}
+	return append(attrs, Fields(err)...)
+}
+
+// exceptionType returns the type of the outermost error, which is not a wrapper
+// of this package, since these types say nothing about the error.
+func exceptionType(err error) string {
+	for isWrapper(err) {
+		next := err.(interface{ Unwrap() error }).Unwrap()
+		if next == nil {
+			break
+		}
+		err = next
+	}
+
+	t := reflect.TypeOf(err)
+	if t.PkgPath() == "" && t.Name() == "" {
+		return t.String() // e.g. *fs.PathError
+	}
+	return fmt.Sprintf("%s.%s", t.PkgPath(), t.Name())
+}
+
+func isWrapper(err error) bool {
+	switch err.(type) {
+	case *baseError, *wrapError, *codeError, *fieldsError, *retryError, *remoteError:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package errors_test
+
+import (
+	"io/fs"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/Zamony/go/errors"
+)
+
+type spanEvent struct {
+	Name  string
+	Attrs map[string]string
+}
+
+type fakeSpan struct {
+	events []spanEvent
+}
+
+func (s *fakeSpan) AddEvent(name string, attrs ...slog.Attr) {
+	event := spanEvent{Name: name, Attrs: make(map[string]string)}
+	for _, attr := range attrs {
+		event.Attrs[attr.Key] = attr.Value.String()
+	}
+	s.events = append(s.events, event)
+}
+
+// opError wraps an error the way many typed errors do,
+// its message ends with the message of the wrapped error.
+type opError struct {
+	Op  string
+	Err error
+}
+
+func (e *opError) Error() string { return e.Op + ": " + e.Err.Error() }
+func (e *opError) Unwrap() error { return e.Err }
+
+func TestRecordError(t *testing.T) {
+	errPath := &fs.PathError{Op: "open", Path: "/tmp", Err: fs.ErrNotExist}
+
+	testCases := []struct {
+		TestName string
+		Error    error
+		Type     string
+	}{
+		{
+			TestName: "New error",
+			Error:    errors.New("new"),
+			Type:     "*errors.errorString",
+		},
+		{
+			TestName: "Wrapped typed error",
+			Error:    errors.WithFields(errors.Wrapf(errPath, "read config"), slog.Int("user_id", 42)),
+			Type:     "*fs.PathError",
+		},
+		{
+			TestName: "Foreign wrapper",
+			Error:    errors.Wrapf(&opError{Op: "dial", Err: errPath}, "connect"),
+			Type:     "*errors_test.opError",
+		},
+		{
+			TestName: "Joined errors",
+			Error:    errors.Join(errors.New("first"), errPath),
+			Type:     "*errors.joinError",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.TestName, func(t *testing.T) {
+			var span fakeSpan
+			errors.RecordError(&span, tc.Error)
+			equal(t, len(span.events), 1)
+
+			event := span.events[0]
+			equal(t, event.Name, errors.ExceptionEventName)
+			equal(t, event.Attrs[errors.ExceptionTypeKey], tc.Type)
+			equal(t, event.Attrs[errors.ExceptionMessageKey], tc.Error.Error())
+			equal(t, event.Attrs[errors.ExceptionStacktraceKey], errors.StackOf(tc.Error).String())
+			for _, attr := range errors.Fields(tc.Error) {
+				equal(t, event.Attrs[attr.Key], attr.Value.String())
+			}
+		})
+	}
+}
+
+func TestRecordErrorStacktrace(t *testing.T) {
+	var span fakeSpan
+	errors.RecordError(&span, errors.New("new"))
+
+	trace := span.events[0].Attrs[errors.ExceptionStacktraceKey]
+	prefix := "github.com/Zamony/go/errors_test.TestRecordErrorStacktrace(...)\n\t"
+	if !strings.HasPrefix(trace, prefix) {
+		t.Errorf("Unexpected stacktrace:\n%s", trace)
+	}
+}
+
+func TestRecordErrorNil(t *testing.T) {
+	var span fakeSpan
+	errors.RecordError(&span, nil)
+	equal(t, len(span.events), 0)
+	equal(t, errors.ExceptionAttrs(nil), []slog.Attr(nil))
+}
@@ -3,27 +3,29 @@ package errors
 import (
 	"fmt"
 	"runtime"
+	"slices"
 	"strings"
 )
 
 // StackTrace returns stacktrace of an error.
 func StackTrace(err error) string {
+	if rerr, ok := err.(remoteStacker); ok {
+		return compactStackTrace(rerr.Stack())
+	}
+
 	sterr, ok := err.(stacktracer) // it is possible to use As() in the future
 	if !ok {
 		return ""
 	}
-
-	frameset := make([]*frameInfo, 0, maxStackDepth)
-	frames := runtime.CallersFrames(sterr.StackTrace())
-	for {
-		frame, more := frames.Next()
-		frameset = append(frameset, parseFrame(&frame))
-		if !more {
-			break
-		}
+	pcs := sharedStack(sterr)
+	if len(pcs) == 0 {
+		return "" // capturing is disabled
 	}
-
-	return stacktrace(frameset)
+	if s := lookup(pcs); s != nil {
+		s.symbolize()
+		return s.trace
+	}
+	return compactStackTrace(symbolize(pcs))
 }
 
 type frameInfo struct {
@@ -31,15 +33,26 @@ type frameInfo struct {
 	Line          int
 }
 
-func parseFrame(frame *runtime.Frame) *frameInfo {
-	fun := frame.Function
+func parseFrame(fun string, line int) *frameInfo {
 	slashIdx := strings.LastIndexByte(fun, '/')
 	if slashIdx >= 0 {
 		fun = fun[slashIdx+1:]
 	}
 
 	pkg, fun, _ := strings.Cut(fun, ".")
-	return &frameInfo{pkg, fun, frame.Line}
+	return &frameInfo{pkg, fun, line}
+}
+
+func compactStackTrace(stack Stack) string {
+	if len(stack) == 0 {
+		return ""
+	}
+
+	frameset := make([]*frameInfo, 0, len(stack))
+	for _, frame := range stack {
+		frameset = append(frameset, parseFrame(frame.Function, frame.Line))
+	}
+	return stacktrace(frameset)
 }
 
 func stacktrace(frameset []*frameInfo) string {
@@ -64,3 +77,82 @@ func stacktrace(frameset []*frameInfo) string {
 
 	return strings.Join(frames, "/")
 }
+
+// Frame is a symbolized stack frame.
+type Frame struct {
+	Function string `json:"function"` // Fully qualified function name.
+	Package  string `json:"package"`  // Import path of the package.
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// Stack is a symbolized stacktrace.
+type Stack []Frame
+
+// StackOf returns symbolized stacktrace of an error.
+// Returns nil if the error has no stacktrace.
+func StackOf(err error) Stack {
+	if rerr, ok := err.(remoteStacker); ok {
+		return rerr.Stack()
+	}
+
+	sterr, ok := err.(stacktracer)
+	if !ok {
+		return nil
+	}
+	return sharedStack(sterr).Symbolize()
+}
+
+// Symbolize resolves program counters into frames.
+// Frames of the stacktraces captured by this package are resolved only once.
+func (s StackFrames) Symbolize() Stack {
+	if is := lookup(s); is != nil {
+		is.symbolize()
+		return slices.Clone(is.stack)
+	}
+	return symbolize(s)
+}
+
+func symbolize(pcs StackFrames) Stack {
+	if len(pcs) == 0 {
+		return nil
+	}
+
+	stack := make(Stack, 0, len(pcs))
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, Frame{
+			Function: frame.Function,
+			Package:  packagePath(frame.Function),
+			File:     frame.File,
+			Line:     frame.Line,
+		})
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// packagePath extracts import path from a fully qualified function name,
+// e.g. "github.com/Zamony/go/errors" from "github.com/Zamony/go/errors.(*baseError).Error".
+func packagePath(fun string) string {
+	slashIdx := max(strings.LastIndexByte(fun, '/'), 0)
+	if dotIdx := strings.IndexByte(fun[slashIdx:], '.'); dotIdx >= 0 {
+		return fun[:slashIdx+dotIdx]
+	}
+	return fun
+}
+
+// String renders the stack like a trace of a panic:
+//
+//	github.com/Zamony/go/errors_test.TestStack(...)
+//		/home/user/go/errors/stack_test.go:12
+func (s Stack) String() string {
+	var b strings.Builder
+	for _, frame := range s {
+		fmt.Fprintf(&b, "%s(...)\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+	}
+	return b.String()
+}
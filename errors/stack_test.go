@@ -0,0 +1,112 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Zamony/go/errors"
+)
+
+func TestStackOf(t *testing.T) {
+	stack := errors.StackOf(newError("new"))
+	if len(stack) < 2 {
+		t.Fatalf("Expected at least 2 frames, got %v", stack)
+	}
+
+	equal(t, stack[0].Function, "github.com/Zamony/go/errors_test.newError")
+	equal(t, stack[0].Package, "github.com/Zamony/go/errors_test")
+	equal(t, stack[0].Line, 12)
+	equal(t, strings.HasSuffix(stack[0].File, "/errors/error_test.go"), true)
+	equal(t, stack[1].Function, "github.com/Zamony/go/errors_test.TestStackOf")
+	equal(t, stack[1].Line, 13)
+
+	equal(t, errors.StackOf(errors.SentinelError("sentinel")), errors.Stack(nil))
+}
+
+func TestStackString(t *testing.T) {
+	stack := errors.Stack{
+		{Function: "main.main", Package: "main", File: "/app/main.go", Line: 10},
+		{Function: "runtime.main", Package: "runtime", File: "/go/src/runtime/proc.go", Line: 283},
+	}
+	want := "main.main(...)\n\t/app/main.go:10\nruntime.main(...)\n\t/go/src/runtime/proc.go:283\n"
+	equal(t, stack.String(), want)
+}
+
+func TestStackJSON(t *testing.T) {
+	stack := errors.Stack{
+		{Function: "github.com/a/b.(*T).Run", Package: "github.com/a/b", File: "/b/t.go", Line: 7},
+	}
+	data, err := json.Marshal(stack)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	equal(t, string(data), `[{"function":"github.com/a/b.(*T).Run","package":"github.com/a/b","file":"/b/t.go","line":7}]`)
+
+	var decoded errors.Stack
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	equal(t, decoded, stack)
+}
+
+func TestSetStackDepth(t *testing.T) {
+	defer errors.SetStackDepth(errors.DefaultStackDepth)
+
+	errors.SetStackDepth(1)
+	err := newError("new")
+	equal(t, errors.StackTrace(err), "errors_test.newError:12")
+
+	errors.SetStackDepth(0)
+	err = newError("new")
+	equal(t, errors.StackTrace(err), "")
+	equal(t, errors.StackOf(err), errors.Stack(nil))
+	equal(t, fmt.Sprintf("%+v", errors.Wrapf(err, "wrapf")), "wrapf: new")
+	equal(t, fmt.Sprintf("%+v", errors.Join(err, errors.SentinelError("foo"))), `["new" "foo"]`)
+}
+
+var benchErr error
+
+func BenchmarkNew(b *testing.B) {
+	defer errors.SetStackDepth(errors.DefaultStackDepth)
+	for _, depth := range []int{0, 8, errors.DefaultStackDepth, 128} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			errors.SetStackDepth(depth)
+			b.ReportAllocs()
+			for b.Loop() {
+				benchErr = errors.New("new")
+			}
+		})
+	}
+}
+
+func BenchmarkWrapf(b *testing.B) {
+	defer errors.SetStackDepth(errors.DefaultStackDepth)
+	errSentinel := errors.SentinelError("sentinel")
+	for _, depth := range []int{0, errors.DefaultStackDepth} {
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			errors.SetStackDepth(depth)
+			b.ReportAllocs()
+			for b.Loop() {
+				benchErr = errors.Wrapf(errSentinel, "wrapf")
+			}
+		})
+	}
+}
+
+func BenchmarkStackTrace(b *testing.B) {
+	err := errors.New("new")
+	b.Run("compact", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = errors.StackTrace(err)
+		}
+	})
+	b.Run("symbolized", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = errors.StackOf(err)
+		}
+	})
+}
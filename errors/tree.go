@@ -0,0 +1,145 @@
+package errors
+
+import (
+	"iter"
+	"slices"
+	"strings"
+)
+
+// Walk returns an iterator over err's tree in depth-first order.
+// It yields every error of the tree along with its depth,
+// err itself has zero depth.
+func Walk(err error) iter.Seq2[error, int] {
+	return func(yield func(error, int) bool) {
+		walk(err, 0, yield)
+	}
+}
+
+func walk(err error, depth int, yield func(error, int) bool) bool {
+	if err == nil {
+		return true
+	}
+	if !yield(err, depth) {
+		return false
+	}
+
+	switch x := err.(type) {
+	case interface{ Unwrap() error }:
+		return walk(x.Unwrap(), depth+1, yield)
+	case interface{ Unwrap() []error }:
+		for _, err := range x.Unwrap() {
+			if !walk(err, depth+1, yield) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// AllOfType returns an iterator over every error in err's tree
+// of the specified type in depth-first order.
+// Like AsType, an error matches if it has As(any) bool method returning true.
+func AllOfType[T error](err error) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for err := range Walk(err) {
+			if x, ok := err.(T); ok {
+				if !yield(x) {
+					return
+				}
+				continue
+			}
+			if x, ok := err.(interface{ As(any) bool }); ok {
+				var target T
+				if x.As(&target) && !yield(target) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Find returns the first error in err's tree in depth-first order,
+// which satisfies the predicate, and true.
+// Returns nil and false if there is no such error.
+func Find(err error, pred func(error) bool) (error, bool) {
+	for err := range Walk(err) {
+		if pred(err) {
+			return err, true
+		}
+	}
+	return nil, false
+}
+
+// FormatTree renders err's tree: every joined error and every error
+// with its own stacktrace is printed indented on a separate line
+// followed by its stacktrace.
+// Unlike %+v, which keeps an error on a single line for logs, the result is multi-line.
+func FormatTree(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	formatTree(&b, err, 0, nil)
+	return b.String()
+}
+
+func formatTree(b *strings.Builder, err error, depth int, printed Stack) {
+	indent := strings.Repeat("    ", depth)
+	b.WriteString(indent)
+	b.WriteString(err.Error())
+	b.WriteByte('\n')
+
+	stack, _ := stackOf(err)
+	children := branches(err, stack)
+	if len(stack) > 0 && !slices.Equal(stack, printed) && !inherits(stack, children) {
+		b.WriteString(indent)
+		b.WriteString("    at ")
+		b.WriteString(StackTrace(err))
+		b.WriteByte('\n')
+		printed = stack
+	}
+
+	for _, child := range children {
+		formatTree(b, child, depth+1, printed)
+	}
+}
+
+// stackOf returns the stacktrace of the error and true if the error carries one,
+// either captured by this package or decoded by Decode.
+func stackOf(err error) (Stack, bool) {
+	switch err.(type) {
+	case stacktracer, remoteStacker:
+		return StackOf(err), true
+	}
+	return nil, false
+}
+
+// branches follows err's chain of wrapped errors and returns either the joined errors
+// or the first wrapped error with a different stacktrace.
+func branches(err error, stack Stack) []error {
+	for {
+		switch x := err.(type) {
+		case interface{ Unwrap() []error }:
+			return x.Unwrap()
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+			if errStack, ok := stackOf(err); ok && !slices.Equal(errStack, stack) {
+				return []error{err}
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// inherits reports whether the stacktrace is borrowed from one of the errors,
+// like a stacktrace of Join is.
+func inherits(stack Stack, errs []error) bool {
+	for _, err := range errs {
+		if errStack, ok := stackOf(err); ok && slices.Equal(errStack, stack) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package errors_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Zamony/go/errors"
+)
+
+func TestWalk(t *testing.T) {
+	errSentinel := errors.SentinelError("sentinel")
+	err := errors.Join(errSentinel, errors.Wrap(fmt.Errorf("wrap: %w", errSentinel)))
+
+	var got []string
+	for err, depth := range errors.Walk(err) {
+		got = append(got, fmt.Sprintf("%d %s", depth, err))
+	}
+	want := []string{
+		`0 ["sentinel" "wrap: sentinel"]`,
+		`1 sentinel`,
+		`1 wrap: sentinel`,
+		`2 wrap: sentinel`,
+		`3 sentinel`,
+	}
+	equal(t, got, want)
+
+	for range errors.Walk(nil) {
+		t.Error("Nil error must have no nodes")
+	}
+	for err := range errors.Walk(err) {
+		equal(t, err.Error(), `["sentinel" "wrap: sentinel"]`)
+		break
+	}
+}
+
+func TestFormatTree(t *testing.T) {
+	testCases := []struct {
+		TestName string
+		Error    error
+		Tree     string
+	}{
+		{
+			TestName: "No error",
+			Error:    nil,
+			Tree:     "",
+		},
+		{
+			TestName: "Sentinel error",
+			Error:    errors.SentinelError("sentinel"),
+			Tree:     "sentinel\n",
+		},
+		{
+			TestName: "Wrapfed error with stack",
+			Error:    errors.Wrapf(newError("new"), "wrapf"),
+			Tree: "wrapf: new\n" +
+				"    at errors_test.newError:12/TestFormatTree/testing.tRunner/runtime.goexit\n",
+		},
+		{
+			TestName: "Wrapped error with hidden stack",
+			Error:    errors.Wrap(fmt.Errorf("hide: %w", newError("new"))),
+			Tree: "hide: new\n" +
+				"    at errors_test.TestFormatTree:60/testing.tRunner/runtime.goexit\n" +
+				"    new\n" +
+				"        at errors_test.newError:12/TestFormatTree/testing.tRunner/runtime.goexit\n",
+		},
+		{
+			TestName: "Joined errors",
+			Error: errors.Wrapf(errors.Join(
+				errors.New("foo"),
+				errors.SentinelError("bar"),
+				errors.Join(newError("baz"), errors.SentinelError("qux")),
+			), "join"),
+			Tree: "join: [\"foo\" \"bar\" \"[\\\"baz\\\" \\\"qux\\\"]\"]\n" +
+				"    foo\n" +
+				"        at errors_test.TestFormatTree:69/testing.tRunner/runtime.goexit\n" +
+				"    bar\n" +
+				"    [\"baz\" \"qux\"]\n" +
+				"        baz\n" +
+				"            at errors_test.newError:12/TestFormatTree/testing.tRunner/runtime.goexit\n" +
+				"        qux\n",
+		},
+		{
+			TestName: "Joined sentinel errors",
+			Error:    errors.Join(errors.SentinelError("foo"), errors.SentinelError("bar")),
+			Tree: "[\"foo\" \"bar\"]\n" +
+				"    at errors_test.TestFormatTree:84/testing.tRunner/runtime.goexit\n" +
+				"    foo\n" +
+				"    bar\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.TestName, func(t *testing.T) {
+			equal(t, errors.FormatTree(tc.Error), tc.Tree)
+		})
+	}
+}
+
+type validationError struct {
+	Field string
+}
+
+func (v *validationError) Error() string {
+	return fmt.Sprintf("invalid %s", v.Field)
+}
+
+type asValidationError struct{}
+
+func (asValidationError) Error() string { return "as" }
+
+func (asValidationError) As(target any) bool {
+	v, ok := target.(**validationError)
+	if ok {
+		*v = &validationError{Field: "as"}
+	}
+	return ok
+}
+
+func TestAllOfType(t *testing.T) {
+	err := errors.Join(
+		errors.Wrap(&validationError{Field: "name"}),
+		errors.New("unrelated"),
+		fmt.Errorf("nested: %w", errors.Join(&validationError{Field: "age"}, asValidationError{})),
+	)
+
+	var fields []string
+	for v := range errors.AllOfType[*validationError](err) {
+		fields = append(fields, v.Field)
+	}
+	equal(t, fields, []string{"name", "age", "as"})
+
+	for range errors.AllOfType[*validationError](err) {
+		break // stops the iteration
+	}
+	for range errors.AllOfType[*validationError](errors.New("new")) {
+		t.Error("Unexpected match")
+	}
+}
+
+func TestFind(t *testing.T) {
+	errSentinel := errors.SentinelError("sentinel")
+	err := errors.Join(errors.New("first"), errors.Wrapf(errSentinel, "second"))
+
+	found, ok := errors.Find(err, func(err error) bool {
+		return err.Error() == "sentinel"
+	})
+	equal(t, found, errSentinel)
+	equal(t, ok, true)
+
+	found, ok = errors.Find(err, func(error) bool { return false })
+	equal(t, found, nil)
+	equal(t, ok, false)
+}
@@ -0,0 +1,259 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+var sentinels = struct {
+	sync.RWMutex
+	byName  map[string]error
+	byError map[error]string
+}{
+	byName:  make(map[string]error),
+	byError: make(map[error]string),
+}
+
+// RegisterSentinel registers the sentinel error under the name,
+// so Decode restores the very same error and Is keeps working
+// on the other side of the wire. Returns the error for convenience:
+//
+//	var ErrNotExists = errors.RegisterSentinel("users.not_exists", errors.SentinelError("doesn't exist"))
+//
+// Panics if the name or the error is already registered,
+// or if the error is not comparable.
+func RegisterSentinel(name string, err error) error {
+	if !isComparable(err) {
+		panic(fmt.Sprintf("errors: sentinel %q is not comparable", err))
+	}
+
+	sentinels.Lock()
+	defer sentinels.Unlock()
+
+	if _, ok := sentinels.byName[name]; ok {
+		panic(fmt.Sprintf("errors: sentinel %q is already registered", name))
+	}
+	if _, ok := sentinels.byError[err]; ok {
+		panic(fmt.Sprintf("errors: sentinel %q is registered under another name", err))
+	}
+	sentinels.byName[name] = err
+	sentinels.byError[err] = name
+	return err
+}
+
+func sentinelName(err error) (string, bool) {
+	if !isComparable(err) {
+		return "", false // can't be a registered sentinel
+	}
+
+	sentinels.RLock()
+	defer sentinels.RUnlock()
+	name, ok := sentinels.byError[err]
+	return name, ok
+}
+
+// isComparable reports whether the error can be used as a map key without a panic.
+func isComparable(err error) bool {
+	return err != nil && reflect.ValueOf(err).Comparable()
+}
+
+func sentinelByName(name string) (error, bool) {
+	sentinels.RLock()
+	defer sentinels.RUnlock()
+	err, ok := sentinels.byName[name]
+	return err, ok
+}
+
+// WireError is the JSON representation of an error tree produced by Encode.
+// Every node is an error with its own message, stacktrace or sentinel identity;
+// wrapping errors that add nothing but a message are folded into their node.
+type WireError struct {
+	Message  string       `json:"message"`
+	Sentinel string       `json:"sentinel,omitempty"` // Registered name of the sentinel error.
+	Code     Code         `json:"code,omitempty"`
+	Stack    Stack        `json:"stack,omitempty"`
+	Wrapped  *WireError   `json:"wrapped,omitempty"`
+	Joined   []*WireError `json:"joined,omitempty"`
+}
+
+// Encode encodes err's tree into JSON, see WireError.
+// Nil error is encoded as null.
+func Encode(err error) ([]byte, error) {
+	if err == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(toWire(err))
+}
+
+// Decode reconstructs an error encoded by Encode.
+// Registered sentinels are restored as is, so Is works for them,
+// and the codes and stacktraces are available via CodeOf, StackOf and StackTrace.
+// Returns false if the data is not a valid encoding.
+func Decode(data []byte) (error, bool) {
+	var w *WireError
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, false
+	}
+	if w == nil {
+		return nil, true
+	}
+	return fromWire(w), true
+}
+
+func toWire(err error) *WireError {
+	w := &WireError{Message: err.Error()}
+	if name, ok := sentinelName(err); ok {
+		w.Sentinel = name
+		return w
+	}
+
+	// Follow the chain of wrapped errors until the tree branches.
+	var children []error
+	joined := false
+	for node := err; node != nil && children == nil; {
+		if c, ok := node.(coder); ok && w.Code == CodeUnknown {
+			w.Code = c.Code()
+		}
+		if w.Stack == nil {
+			w.Stack = StackOf(node)
+		}
+
+		switch x := node.(type) {
+		case interface{ Unwrap() []error }:
+			children, joined, node = x.Unwrap(), true, nil
+			if inherits(w.Stack, children) {
+				w.Stack = nil
+			}
+		case interface{ Unwrap() error }:
+			node = x.Unwrap()
+			if node != nil && isBranch(node, w.Stack) {
+				children = []error{node}
+			}
+		default:
+			node = nil
+		}
+	}
+
+	if joined {
+		for _, child := range children {
+			w.Joined = append(w.Joined, toWire(child))
+		}
+	} else if len(children) > 0 {
+		w.Wrapped = toWire(children[0])
+	}
+	return w
+}
+
+// isBranch reports whether the wrapped error has to be encoded as a separate node.
+func isBranch(err error, stack Stack) bool {
+	if _, ok := sentinelName(err); ok {
+		return true
+	}
+	if _, ok := err.(interface{ Unwrap() []error }); ok {
+		return true
+	}
+	errStack := StackOf(err)
+	return stack != nil && errStack != nil && !slices.Equal(errStack, stack)
+}
+
+func fromWire(w *WireError) error {
+	if w.Sentinel != "" {
+		if err, ok := sentinelByName(w.Sentinel); ok {
+			return err
+		}
+	}
+
+	stack := w.Stack
+	var err error
+	if len(w.Joined) > 0 {
+		errs := make([]error, 0, len(w.Joined))
+		for _, child := range w.Joined {
+			if child == nil {
+				continue
+			}
+			cerr := fromWire(child)
+			if stack == nil {
+				stack = StackOf(cerr) // inherit like Join does
+			}
+			errs = append(errs, cerr)
+		}
+		err = &remoteJoinError{remote{w.Message, stack}, errs}
+	} else {
+		rerr := &remoteError{remote: remote{w.Message, stack}}
+		if w.Wrapped != nil {
+			rerr.err = fromWire(w.Wrapped)
+		}
+		err = rerr
+	}
+
+	if w.Code != CodeUnknown {
+		// The stacktrace stays on the outermost error like WithCode does.
+		err = &remoteError{remote{w.Message, stack}, &codeError{err, w.Code}}
+	}
+	return err
+}
+
+// wrapRemote gives the error the stacktrace of a decoded error it wraps,
+// since a decoded stacktrace can't be shared as program counters.
+func wrapRemote(err error, stack Stack) error {
+	if joined, ok := err.(*joinError); ok {
+		return &remoteJoinError{remote{err.Error(), stack}, joined.errors}
+	}
+	return &remoteError{remote{err.Error(), stack}, err}
+}
+
+// remoteStacker is implemented by the decoded errors,
+// which have symbolized stacktraces instead of program counters.
+type remoteStacker interface {
+	Stack() Stack
+}
+
+type remote struct {
+	msg   string
+	stack Stack
+}
+
+func (r *remote) Error() string {
+	return r.msg
+}
+
+func (r *remote) Stack() Stack {
+	return r.stack
+}
+
+func (r *remote) Format(state fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if state.Flag('+') {
+			if trace := StackTrace(r); trace != "" {
+				_, _ = fmt.Fprintf(state, "%s: %s", r.Error(), trace)
+				return
+			}
+		}
+		fallthrough
+	case 's', 'q':
+		io.WriteString(state, r.Error())
+	}
+}
+
+type remoteError struct {
+	remote
+	err error
+}
+
+func (r *remoteError) Unwrap() error {
+	return r.err
+}
+
+type remoteJoinError struct {
+	remote
+	errs []error
+}
+
+func (r *remoteJoinError) Unwrap() []error {
+	return r.errs
+}
@@ -0,0 +1,225 @@
+package errors_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/Zamony/go/errors"
+)
+
+var errWireNotFound = errors.RegisterSentinel("wire_test.not_found", errors.SentinelError("not found"))
+
+func roundTrip(t *testing.T, err error) error {
+	t.Helper()
+	data, encErr := errors.Encode(err)
+	if encErr != nil {
+		t.Fatalf("Encode: %v", encErr)
+	}
+	decoded, ok := errors.Decode(data)
+	if !ok {
+		t.Fatalf("Decode failed: %s", data)
+	}
+	return decoded
+}
+
+func TestWireRoundTrip(t *testing.T) {
+	errUnregistered := errors.SentinelError("unregistered")
+	errWrapped := errors.Wrapf(errWireNotFound, "get user")
+
+	testCases := []struct {
+		TestName string
+		Error    error
+		Is       error
+		IsNot    error
+	}{
+		{
+			TestName: "Registered sentinel",
+			Error:    errWireNotFound,
+			Is:       errWireNotFound,
+		},
+		{
+			TestName: "Wrapped registered sentinel",
+			Error:    errWrapped,
+			Is:       errWireNotFound,
+		},
+		{
+			TestName: "Registered sentinel with code",
+			Error:    errors.WithCode(errWrapped, errors.CodeNotFound),
+			Is:       errWireNotFound,
+		},
+		{
+			TestName: "Joined registered sentinel",
+			Error:    errors.Join(errors.New("first"), errWrapped),
+			Is:       errWireNotFound,
+		},
+		{
+			TestName: "Unregistered sentinel",
+			Error:    errors.Wrap(errUnregistered),
+			IsNot:    errUnregistered,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.TestName, func(t *testing.T) {
+			got := roundTrip(t, tc.Error)
+			equal(t, got.Error(), tc.Error.Error())
+			equal(t, errors.CodeOf(got), errors.CodeOf(tc.Error))
+			equal(t, errors.StackOf(got), errors.StackOf(tc.Error))
+			equal(t, errors.StackTrace(got), errors.StackTrace(tc.Error))
+			if tc.Is != nil {
+				equal(t, errors.Is(got, tc.Is), true)
+			}
+			if tc.IsNot != nil {
+				equal(t, errors.Is(got, tc.IsNot), false)
+			}
+		})
+	}
+}
+
+func TestWireFormat(t *testing.T) {
+	errFirst := errors.New("first")
+	errSecond := errors.WithCode(errors.Wrapf(errWireNotFound, "second"), errors.CodeNotFound)
+	err := fmt.Errorf("process: %w", errors.Join(errFirst, errSecond))
+
+	data, encErr := errors.Encode(err)
+	equal(t, encErr, nil)
+
+	var got errors.WireError
+	equal(t, json.Unmarshal(data, &got), nil)
+
+	stripStacks(&got)
+	equal(t, got, errors.WireError{
+		Message: err.Error(),
+		Wrapped: &errors.WireError{
+			Message: errors.Join(errFirst, errSecond).Error(),
+			Joined: []*errors.WireError{
+				{Message: "first"},
+				{
+					Message: "second: not found",
+					Code:    errors.CodeNotFound,
+					Wrapped: &errors.WireError{Message: "not found", Sentinel: "wire_test.not_found"},
+				},
+			},
+		},
+	})
+}
+
+// stripStacks removes stacktraces from the tree, since they depend on the environment.
+func stripStacks(w *errors.WireError) {
+	w.Stack = nil
+	if w.Wrapped != nil {
+		stripStacks(w.Wrapped)
+	}
+	for _, child := range w.Joined {
+		stripStacks(child)
+	}
+}
+
+func TestWireJoinedStacks(t *testing.T) {
+	errFirst := errors.New("first")
+	errSecond := errors.New("second")
+
+	got := roundTrip(t, errors.Join(errFirst, errSecond))
+	joined, ok := got.(interface{ Unwrap() []error })
+	equal(t, ok, true)
+
+	errs := joined.Unwrap()
+	equal(t, len(errs), 2)
+	equal(t, errors.StackOf(errs[0]), errors.StackOf(errFirst))
+	equal(t, errors.StackOf(errs[1]), errors.StackOf(errSecond))
+	equal(t, fmt.Sprintf("%+v", errs[1]), fmt.Sprintf("%+v", errSecond))
+
+	// Decoded errors can be encoded again for the next hop.
+	equal(t, errors.StackOf(roundTrip(t, got)), errors.StackOf(got))
+}
+
+func TestWireNil(t *testing.T) {
+	data, err := errors.Encode(nil)
+	equal(t, string(data), "null")
+	equal(t, err, nil)
+
+	decoded, ok := errors.Decode(data)
+	equal(t, decoded, nil)
+	equal(t, ok, true)
+}
+
+func TestWireInvalid(t *testing.T) {
+	decoded, ok := errors.Decode([]byte("{"))
+	equal(t, decoded, nil)
+	equal(t, ok, false)
+}
+
+func TestWireUnknownSentinel(t *testing.T) {
+	got, ok := errors.Decode([]byte(`{"message":"gone","sentinel":"wire_test.unknown","code":"unavailable"}`))
+	equal(t, ok, true)
+	equal(t, got.Error(), "gone")
+	equal(t, errors.CodeOf(got), errors.CodeUnavailable)
+}
+
+func TestRegisterSentinelTwice(t *testing.T) {
+	defer func() {
+		equal(t, recover() != nil, true)
+	}()
+	errors.RegisterSentinel("wire_test.not_found", errors.SentinelError("not found"))
+}
+
+func TestCodeText(t *testing.T) {
+	for code := errors.CodeUnknown; code <= errors.CodeInternal; code++ {
+		text, err := code.MarshalText()
+		equal(t, err, nil)
+
+		var got errors.Code
+		equal(t, got.UnmarshalText(text), nil)
+		equal(t, got, code)
+	}
+}
+
+type sliceError []string
+
+func (s sliceError) Error() string { return fmt.Sprint([]string(s)) }
+
+func TestWireUncomparable(t *testing.T) {
+	err := errors.Wrapf(sliceError{"a"}, "ctx")
+	got := roundTrip(t, err)
+	equal(t, got.Error(), err.Error())
+
+	defer func() {
+		equal(t, recover() != nil, true)
+	}()
+	errors.RegisterSentinel("wire_test.slice", sliceError{"a"})
+}
+
+func TestWireFormatTree(t *testing.T) {
+	err := errors.Join(errors.New("first"), errors.Wrapf(errWireNotFound, "second"))
+	equal(t, errors.FormatTree(roundTrip(t, err)), errors.FormatTree(err))
+}
+
+func TestWireWrapDecoded(t *testing.T) {
+	err := errors.New("remote")
+	decoded := roundTrip(t, err)
+	collector := errors.NewCollector(0)
+	collector.Add(decoded)
+	collector.Add(errors.New("local"))
+
+	testCases := []struct {
+		TestName string
+		Error    error
+	}{
+		{TestName: "Wrap", Error: errors.Wrap(decoded)},
+		{TestName: "Wrapf", Error: errors.Wrapf(decoded, "call users")},
+		{TestName: "WithCode", Error: errors.WithCode(decoded, errors.CodeNotFound)},
+		{TestName: "WithFields", Error: errors.WithFields(decoded, slog.Int("user_id", 42))},
+		{TestName: "Retryable", Error: errors.Retryable(decoded)},
+		{TestName: "Join", Error: errors.Join(decoded, errors.New("local"))},
+		{TestName: "Collector", Error: collector.Err()},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.TestName, func(t *testing.T) {
+			equal(t, errors.StackOf(tc.Error), errors.StackOf(err))
+			equal(t, errors.StackTrace(tc.Error), errors.StackTrace(err))
+			equal(t, errors.Is(tc.Error, decoded), true)
+			equal(t, errors.StackOf(roundTrip(t, tc.Error)), errors.StackOf(err))
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package golimit
+
+import (
+	"sync"
+	"time"
+)
+
+// GCRA is a goroutine-safe rate-limiter,
+// which implements generic cell rate algorithm.
+// It spaces actions evenly allowing bursts up to the limit,
+// like Limiter does, but keeps a single timestamp
+// in integer nanoseconds.
+type GCRA struct {
+	mu       sync.Mutex
+	period   time.Duration
+	interval time.Duration // emission interval of an action with unit weight
+	tat      time.Time     // theoretical arrival time of the next action
+}
+
+// NewGCRA creates a new limiter with specified limit and period.
+// It panics if limit or period is not positive.
+func NewGCRA(limit float64, period time.Duration) *GCRA {
+	if limit <= 0 || period <= 0 {
+		panic("golimit: non-positive limit or period for NewGCRA")
+	}
+	return &GCRA{
+		period:   period,
+		interval: time.Duration(float64(period) / limit),
+	}
+}
+
+// Limit returns true if an action was rejected.
+// It accepts positive weight of an action as an argument.
+func (g *GCRA) Limit(n float64) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	tat = tat.Add(time.Duration(n * float64(g.interval)))
+	if tat.Sub(now) > g.period {
+		return true
+	}
+
+	g.tat = tat
+	return false
+}
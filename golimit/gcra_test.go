@@ -0,0 +1,29 @@
+package golimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGCRA_Limit(t *testing.T) {
+	const (
+		limit  = 10
+		period = 200 * time.Millisecond
+	)
+
+	lim := NewGCRA(limit, period)
+	for !lim.Limit(1) {
+	}
+
+	// A single action is allowed every period/limit.
+	time.Sleep(period / 2)
+	allowed := 0
+	for i := 0; i < limit; i++ {
+		if !lim.Limit(1) {
+			allowed++
+		}
+	}
+	if allowed < limit/2-1 || allowed > limit/2+1 {
+		t.Fatalf("Got %d allowed, want about %d", allowed, limit/2)
+	}
+}
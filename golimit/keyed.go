@@ -0,0 +1,113 @@
+package golimit
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Keyed is a goroutine-safe rate-limiter with a separate token bucket
+// per key, e.g. per API key or per client IP. All the buckets share the same
+// limit and period and are created on the first use of a key.
+type Keyed struct {
+	mu      sync.Mutex
+	limit   float64
+	period  time.Duration
+	ttl     time.Duration
+	maxKeys int
+	buckets map[string]*list.Element
+	lru     list.List // of *keyedBucket, the most recently used at the front
+}
+
+type keyedBucket struct {
+	key  string
+	lim  *Limiter
+	used time.Time
+}
+
+// NewKeyed creates a new keyed limiter with specified limit and period per key.
+// Buckets not used for ttl are evicted, so ttl should be at least period
+// to evict only full buckets. A reserved action counts as a use of a bucket
+// at the moment it is allowed, so buckets in debt after Reserve or Wait are kept.
+// If there are more than maxKeys buckets, the least recently used one
+// is evicted even if it is not full. Non-positive ttl or maxKeys
+// disables the corresponding eviction.
+func NewKeyed(limit float64, period, ttl time.Duration, maxKeys int) *Keyed {
+	return &Keyed{
+		limit:   limit,
+		period:  period,
+		ttl:     ttl,
+		maxKeys: maxKeys,
+		buckets: make(map[string]*list.Element),
+	}
+}
+
+// Limit returns true if an action for the key was rejected.
+// It accepts positive weight of an action as an argument.
+func (k *Keyed) Limit(key string, n float64) bool {
+	return k.bucket(key).Limit(n)
+}
+
+// Reserve reserves weight n for an action for the key, see Limiter.Reserve.
+func (k *Keyed) Reserve(key string, n float64) *Reservation {
+	return k.bucket(key).Reserve(n)
+}
+
+// Wait blocks until an action for the key with weight n is allowed,
+// see Limiter.Wait.
+func (k *Keyed) Wait(ctx context.Context, key string, n float64) error {
+	return k.bucket(key).Wait(ctx, n)
+}
+
+// Up increases the current possible weight for the key by n.
+func (k *Keyed) Up(key string, n float64) {
+	k.bucket(key).Up(n)
+}
+
+// Len returns the number of keys having a bucket.
+func (k *Keyed) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.evict(time.Now())
+	return len(k.buckets)
+}
+
+func (k *Keyed) bucket(key string) *Limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	now := time.Now()
+
+	if elem, ok := k.buckets[key]; ok {
+		bucket := elem.Value.(*keyedBucket)
+		bucket.used = now
+		k.lru.MoveToFront(elem)
+		k.evict(now)
+		return bucket.lim
+	}
+
+	bucket := &keyedBucket{key: key, lim: New(k.limit, k.period), used: now}
+	k.buckets[key] = k.lru.PushFront(bucket)
+	k.evict(now)
+	return bucket.lim
+}
+
+// evict removes idle buckets and the least recently used ones above maxKeys.
+func (k *Keyed) evict(now time.Time) {
+	for elem := k.lru.Back(); elem != nil; {
+		bucket := elem.Value.(*keyedBucket)
+		prev := elem.Prev()
+		idle := k.ttl > 0 && now.Sub(bucket.used) >= k.ttl
+		overflow := k.maxKeys > 0 && len(k.buckets) > k.maxKeys
+		if !idle && !overflow {
+			return
+		}
+		// The bucket is still in use, if the reserved action happened recently
+		// or is yet to happen.
+		if overflow || now.Sub(bucket.lim.reservedUntil()) >= k.ttl {
+			k.lru.Remove(elem)
+			delete(k.buckets, bucket.key)
+		}
+		elem = prev
+	}
+}
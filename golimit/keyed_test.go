@@ -0,0 +1,123 @@
+package golimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyed_Limit(t *testing.T) {
+	const (
+		limit  = 3
+		period = time.Second
+	)
+
+	lim := NewKeyed(limit, period, 0, 0)
+	for _, key := range []string{"a", "b"} {
+		allowed := 0
+		for i := 0; i < 2*limit; i++ {
+			if !lim.Limit(key, 1) {
+				allowed++
+			}
+		}
+		if allowed != limit {
+			t.Fatalf("Got %d allowed for key %q, want %d", allowed, key, limit)
+		}
+	}
+
+	if got := lim.Len(); got != 2 {
+		t.Fatalf("Got %d keys, want 2", got)
+	}
+}
+
+func TestKeyed_TTL(t *testing.T) {
+	const (
+		limit = 1
+		ttl   = 100 * time.Millisecond
+	)
+
+	lim := NewKeyed(limit, time.Hour, ttl, 0)
+	lim.Limit("a", limit)
+	lim.Limit("b", limit)
+	time.Sleep(ttl / 2)
+	lim.Limit("b", limit)
+	time.Sleep(ttl / 2)
+
+	if got := lim.Len(); got != 1 {
+		t.Fatalf("Got %d keys, want 1", got)
+	}
+	if lim.Limit("a", limit) {
+		t.Fatal("Got rejected action for evicted key")
+	}
+	if !lim.Limit("b", limit) {
+		t.Fatal("Got allowed action for used key")
+	}
+}
+
+func TestKeyed_TTLDebt(t *testing.T) {
+	const (
+		limit  = 1
+		period = 100 * time.Millisecond
+		ttl    = period
+	)
+
+	lim := NewKeyed(limit, period, ttl, 0)
+	lim.Limit("a", limit)
+	lim.Reserve("a", limit) // allowed in a period
+	time.Sleep(ttl + ttl/2)
+
+	if got := lim.Len(); got != 1 {
+		t.Fatalf("Got %d keys, want 1", got)
+	}
+	if !lim.Limit("a", limit) {
+		t.Fatal("Got allowed action for key in debt")
+	}
+}
+
+func TestKeyed_MaxKeys(t *testing.T) {
+	const (
+		limit   = 1
+		maxKeys = 2
+	)
+
+	lim := NewKeyed(limit, time.Hour, 0, maxKeys)
+	for _, key := range []string{"a", "b", "a", "c"} {
+		lim.Limit(key, limit)
+	}
+
+	if got := lim.Len(); got != maxKeys {
+		t.Fatalf("Got %d keys, want %d", got, maxKeys)
+	}
+	if lim.Limit("b", limit) {
+		t.Fatal("Got rejected action for least recently used key")
+	}
+	if !lim.Limit("c", limit) {
+		t.Fatal("Got allowed action for recently used key")
+	}
+}
+
+func TestKeyed_Concurrent(t *testing.T) {
+	const (
+		limit   = 10
+		nkeys   = 50
+		maxKeys = 20
+	)
+
+	lim := NewKeyed(limit, time.Hour, time.Minute, maxKeys)
+	var wg sync.WaitGroup
+	for i := 0; i < nkeys; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := string(rune('a' + i))
+			for j := 0; j < limit; j++ {
+				lim.Limit(key, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := lim.Len(); got != maxKeys {
+		t.Fatalf("Got %d keys, want %d", got, maxKeys)
+	}
+}
@@ -1,10 +1,32 @@
 package golimit
 
 import (
+	"context"
+	"errors"
+	"math"
 	"sync"
 	"time"
 )
 
+// ErrExceedsLimit is returned by Wait if the weight of an action
+// exceeds the limit, so it can never be allowed.
+var ErrExceedsLimit = errors.New("golimit: weight exceeds limit")
+
+// RateLimiter is implemented by all the limiters of this package,
+// so the algorithms are interchangeable.
+type RateLimiter interface {
+	// Limit returns true if an action was rejected.
+	// It accepts positive weight of an action as an argument.
+	Limit(n float64) bool
+}
+
+var (
+	_ RateLimiter = (*Limiter)(nil)
+	_ RateLimiter = (*SlidingLog)(nil)
+	_ RateLimiter = (*SlidingWindow)(nil)
+	_ RateLimiter = (*GCRA)(nil)
+)
+
 // Limiter is a goroutine-safe rate-limiter,
 // which implements token-bucket algorithm.
 type Limiter struct {
@@ -13,6 +35,7 @@ type Limiter struct {
 	curr   float64
 	period float64
 	last   float64
+	lastAt float64 // when the latest reserved action is allowed
 }
 
 // New creates a new limiter with specified limit and period.
@@ -31,19 +54,149 @@ func New(limit float64, period time.Duration) *Limiter {
 func (l *Limiter) Limit(n float64) bool {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	now := float64(time.Now().UnixNano())
+	l.advance(now())
+
+	if l.curr < n {
+		return true
+	}
+
+	l.curr -= n
+	return false
+}
+
+// advance refills the bucket up to the moment.
+func (l *Limiter) advance(now float64) {
 	l.curr += (now - l.last) * l.limit / l.period
 	l.last = now
 	if l.curr > l.limit {
 		l.curr = l.limit
 	}
+}
+
+func now() float64 {
+	return float64(time.Now().UnixNano())
+}
+
+// Reservation holds weight reserved by Reserve
+// until the action is allowed to happen.
+type Reservation struct {
+	lim *Limiter
+	n   float64
+	at  float64 // when the action is allowed, in nanoseconds
+	ok  bool
+}
+
+// Reserve reserves weight n for an action, which has to be delayed
+// by Reservation.Delay. The action is never rejected unless n exceeds the limit,
+// but other actions are throttled until the reserved weight is refilled.
+func (l *Limiter) Reserve(n float64) *Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := now()
+	l.advance(now)
+
+	if n > l.limit {
+		return &Reservation{lim: l}
+	}
 
+	at := now
 	if l.curr < n {
-		return true
+		at += (n - l.curr) * l.period / l.limit
 	}
-
 	l.curr -= n
-	return false
+	l.lastAt = at
+	return &Reservation{lim: l, n: n, at: at, ok: true}
+}
+
+// OK returns false if the weight exceeds the limit,
+// so the action is never allowed.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay returns how long to wait before the action is allowed.
+// Returns math.MaxInt64 duration if the reservation is not OK.
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return math.MaxInt64
+	}
+
+	delay := r.at - now()
+	if delay < 0 {
+		return 0
+	}
+	return time.Duration(delay)
+}
+
+// Cancel returns the reserved weight back to the limiter,
+// if the action is not allowed yet.
+// The weight already consumed by later reservations is not returned,
+// since their delays were calculated with it.
+func (r *Reservation) Cancel() {
+	l := r.lim
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := now()
+	if !r.ok || r.n == 0 || r.at <= now {
+		return
+	}
+
+	restore := r.n - (l.lastAt-r.at)*l.limit/l.period
+	r.n = 0
+	if restore <= 0 {
+		return
+	}
+
+	l.advance(now)
+	l.curr += restore
+	if l.curr > l.limit {
+		l.curr = l.limit
+	}
+	if r.at >= l.lastAt {
+		if prev := r.at - restore*l.period/l.limit; prev >= now {
+			l.lastAt = prev
+		}
+	}
+}
+
+// reservedUntil returns when the latest reserved action is allowed.
+func (l *Limiter) reservedUntil() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return time.Unix(0, int64(l.lastAt))
+}
+
+// Wait blocks until an action with weight n is allowed.
+// It returns an error if the context is done before that,
+// or if the deadline of the context is too close to wait,
+// or if n exceeds the limit.
+func (l *Limiter) Wait(ctx context.Context, n float64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r := l.Reserve(n)
+	if !r.OK() {
+		return ErrExceedsLimit
+	}
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		r.Cancel()
+		return context.DeadlineExceeded
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
 }
 
 // Up increases the current possible weight by n.
@@ -1,6 +1,7 @@
 package golimit
 
 import (
+	"context"
 	"testing"
 	"time"
 )
@@ -54,3 +55,178 @@ func TestLimiter_Up(t *testing.T) {
 		t.Fatalf("Got %d allowed, want %d", allowed, want)
 	}
 }
+
+func TestLimiter_Reserve(t *testing.T) {
+	const (
+		limit  = 10
+		period = time.Second
+	)
+
+	lim := New(limit, period)
+	if r := lim.Reserve(limit); !r.OK() || r.Delay() != 0 {
+		t.Fatalf("Got delay %v, want 0", r.Delay())
+	}
+
+	r := lim.Reserve(limit / 2)
+	if delay := r.Delay(); delay < period/2-10*time.Millisecond || delay > period/2 {
+		t.Fatalf("Got delay %v, want %v", delay, period/2)
+	}
+	if !lim.Limit(1) {
+		t.Fatal("Got allowed action, while weight is reserved")
+	}
+
+	r.Cancel()
+	r.Cancel() // no-op
+	r = lim.Reserve(limit / 2)
+	if delay := r.Delay(); delay < period/2-10*time.Millisecond || delay > period/2 {
+		t.Fatalf("Got delay %v after cancel, want %v", delay, period/2)
+	}
+
+	if r := lim.Reserve(limit + 1); r.OK() {
+		t.Fatal("Got OK reservation exceeding the limit")
+	}
+}
+
+func TestLimiter_CancelConsumed(t *testing.T) {
+	const (
+		limit  = 10
+		period = time.Second
+	)
+
+	lim := New(limit, period)
+	if lim.Limit(limit) {
+		t.Fatal("Got rejected action")
+	}
+
+	// The second reservation is delayed by the weight of the first one,
+	// so cancelling the first one returns nothing.
+	first := lim.Reserve(limit)
+	lim.Reserve(limit)
+	first.Cancel()
+	last := lim.Reserve(limit)
+	if delay := last.Delay(); delay < 3*period-10*time.Millisecond || delay > 3*period {
+		t.Fatalf("Got delay %v, want %v", delay, 3*period)
+	}
+
+	// Nothing consumed the weight of the last reservation yet.
+	last.Cancel()
+	if delay := lim.Reserve(limit).Delay(); delay < 3*period-10*time.Millisecond || delay > 3*period {
+		t.Fatalf("Got delay %v after cancel, want %v", delay, 3*period)
+	}
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	const (
+		limit  = 10
+		period = 100 * time.Millisecond
+	)
+
+	lim := New(limit, period)
+	if err := lim.Wait(context.Background(), limit); err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+
+	start := time.Now()
+	if err := lim.Wait(context.Background(), limit/2); err != nil {
+		t.Fatalf("Got error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < period/2-5*time.Millisecond {
+		t.Fatalf("Waited %v, want at least %v", elapsed, period/2)
+	}
+
+	if err := lim.Wait(context.Background(), limit+1); err != ErrExceedsLimit {
+		t.Fatalf("Got error %v, want %v", err, ErrExceedsLimit)
+	}
+}
+
+func TestLimiter_WaitContext(t *testing.T) {
+	const (
+		limit  = 1
+		period = time.Second
+	)
+
+	lim := New(limit, period)
+	if lim.Limit(limit) {
+		t.Fatal("Got rejected action")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := lim.Wait(ctx, limit); err != context.DeadlineExceeded {
+		t.Fatalf("Got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := lim.Wait(ctx, limit); err != context.Canceled {
+		t.Fatalf("Got error %v, want %v", err, context.Canceled)
+	}
+	if err := lim.Wait(ctx, limit); err != context.Canceled {
+		t.Fatalf("Got error %v for done context, want %v", err, context.Canceled)
+	}
+
+	// Weight of the cancelled waits is returned.
+	if delay := lim.Reserve(limit).Delay(); delay > period {
+		t.Fatalf("Got delay %v, want at most %v", delay, period)
+	}
+}
+
+func TestRateLimiter_Limit(t *testing.T) {
+	const (
+		limit  = 10
+		period = 50 * time.Millisecond
+	)
+
+	testCases := []struct {
+		TestName string
+		New      func() RateLimiter
+	}{
+		{"Token bucket", func() RateLimiter { return New(limit, period) }},
+		{"Sliding log", func() RateLimiter { return NewSlidingLog(limit, period) }},
+		{"Sliding window", func() RateLimiter { return NewSlidingWindow(limit, period) }},
+		{"GCRA", func() RateLimiter { return NewGCRA(limit, period) }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.TestName, func(t *testing.T) {
+			lim := tc.New()
+			for i := 0; i < limit; i++ {
+				if lim.Limit(1) {
+					t.Fatalf("Got rejected action %d", i)
+				}
+			}
+			if !lim.Limit(1) {
+				t.Fatal("Got allowed action above the limit")
+			}
+
+			time.Sleep(2 * period)
+			if lim.Limit(limit) {
+				t.Fatal("Got rejected action after the period")
+			}
+			if !lim.Limit(limit + 1) {
+				t.Fatal("Got allowed action exceeding the limit")
+			}
+		})
+	}
+}
+
+func TestRateLimiter_NonPositive(t *testing.T) {
+	testCases := []struct {
+		TestName string
+		New      func()
+	}{
+		{"SlidingLog", func() { NewSlidingLog(1, 0) }},
+		{"SlidingWindow", func() { NewSlidingWindow(1, 0) }},
+		{"GCRA period", func() { NewGCRA(1, 0) }},
+		{"GCRA limit", func() { NewGCRA(0, time.Second) }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.TestName, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("Got no panic")
+				}
+			}()
+			tc.New()
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package golimit
+
+import (
+	"sync"
+	"time"
+)
+
+// SlidingLog is a goroutine-safe rate-limiter,
+// which implements sliding-window-log algorithm:
+// the total weight of actions allowed during any window never exceeds the limit.
+// It remembers every allowed action of the last window,
+// so memory usage grows with the limit.
+type SlidingLog struct {
+	mu     sync.Mutex
+	limit  float64
+	window time.Duration
+	log    []logEntry // allowed actions from the oldest to the newest
+	sum    float64    // total weight of log
+}
+
+type logEntry struct {
+	at     time.Time
+	weight float64
+}
+
+// NewSlidingLog creates a new limiter allowing limit per window.
+// It panics if window is not positive.
+func NewSlidingLog(limit float64, window time.Duration) *SlidingLog {
+	if window <= 0 {
+		panic("golimit: non-positive window for NewSlidingLog")
+	}
+	return &SlidingLog{
+		limit:  limit,
+		window: window,
+	}
+}
+
+// Limit returns true if an action was rejected.
+// It accepts positive weight of an action as an argument.
+func (s *SlidingLog) Limit(n float64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+
+	expired := 0
+	for expired < len(s.log) && now.Sub(s.log[expired].at) >= s.window {
+		s.sum -= s.log[expired].weight
+		expired++
+	}
+	if expired > 0 {
+		s.log = append(s.log[:0], s.log[expired:]...)
+	}
+	if len(s.log) == 0 {
+		s.sum = 0 // drop accumulated rounding errors
+	}
+
+	if s.sum+n > s.limit {
+		return true
+	}
+
+	s.log = append(s.log, logEntry{now, n})
+	s.sum += n
+	return false
+}
+
+// SlidingWindow is a goroutine-safe rate-limiter,
+// which implements sliding-window-counter algorithm:
+// the weight of the previous fixed window is counted in proportion
+// to its overlap with the sliding window. It approximates SlidingLog
+// using constant memory.
+type SlidingWindow struct {
+	mu     sync.Mutex
+	limit  float64
+	window time.Duration
+	start  time.Time // start of the current fixed window
+	prev   float64   // weight of the previous fixed window
+	curr   float64   // weight of the current fixed window
+}
+
+// NewSlidingWindow creates a new limiter allowing limit per window.
+// It panics if window is not positive.
+func NewSlidingWindow(limit float64, window time.Duration) *SlidingWindow {
+	if window <= 0 {
+		panic("golimit: non-positive window for NewSlidingWindow")
+	}
+	return &SlidingWindow{
+		limit:  limit,
+		window: window,
+		start:  time.Now(),
+	}
+}
+
+// Limit returns true if an action was rejected.
+// It accepts positive weight of an action as an argument.
+func (s *SlidingWindow) Limit(n float64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+
+	windows := now.Sub(s.start) / s.window
+	if windows > 0 {
+		s.prev = 0
+		if windows == 1 {
+			s.prev = s.curr
+		}
+		s.curr = 0
+		s.start = s.start.Add(windows * s.window)
+	}
+
+	overlap := float64(s.window-now.Sub(s.start)) / float64(s.window)
+	if s.prev*overlap+s.curr+n > s.limit {
+		return true
+	}
+
+	s.curr += n
+	return false
+}
@@ -0,0 +1,70 @@
+package golimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlidingLog_Limit(t *testing.T) {
+	const (
+		limit  = 4
+		window = 100 * time.Millisecond
+	)
+
+	lim := NewSlidingLog(limit, window)
+	lim.Limit(limit / 2)
+	time.Sleep(window / 2)
+	lim.Limit(limit / 2)
+	time.Sleep(window / 2)
+
+	// Only the first half of the weight has left the window.
+	if !lim.Limit(limit/2 + 1) {
+		t.Fatal("Got allowed action exceeding the limit within the window")
+	}
+	if lim.Limit(limit / 2) {
+		t.Fatal("Got rejected action within the limit")
+	}
+}
+
+func TestSlidingLog_Expire(t *testing.T) {
+	const (
+		limit  = 3
+		window = 20 * time.Millisecond
+	)
+
+	lim := NewSlidingLog(limit, window)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < limit; j++ {
+			if lim.Limit(1) {
+				t.Fatalf("Got rejected action %d in iteration %d", j, i)
+			}
+		}
+		time.Sleep(window)
+	}
+	if len(lim.log) > limit {
+		t.Fatalf("Got %d actions in the log, want at most %d", len(lim.log), limit)
+	}
+}
+
+func TestSlidingWindow_Limit(t *testing.T) {
+	const (
+		limit  = 10
+		window = 200 * time.Millisecond
+	)
+
+	lim := NewSlidingWindow(limit, window)
+	lim.start = time.Now().Add(-window) // the previous window is full
+	lim.curr = limit
+
+	// About a half of the previous window overlaps with the sliding window.
+	time.Sleep(window / 2)
+	allowed := 0
+	for i := 0; i < limit; i++ {
+		if !lim.Limit(1) {
+			allowed++
+		}
+	}
+	if allowed < limit/2-1 || allowed > limit/2+1 {
+		t.Fatalf("Got %d allowed, want about %d", allowed, limit/2)
+	}
+}